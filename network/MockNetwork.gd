@@ -319,7 +319,36 @@ func _handle_category_chosen(event: Dictionary) -> void:
 	_advance_turn()
 
 
-func _handle_end_turn(_event: Dictionary) -> void:
+func _handle_end_turn(event: Dictionary) -> void:
+	# Like the server, ending a turn scratches a category for 0
+	var player_id: String = local_player_id
+	if player_id != turn_order[current_player_index]:
+		return
+	var category: String = event.get("category", "")
+	var used: Array = player_used_categories.get(player_id, [])
+	if category == "" or category in used:
+		get_node("/root/Logger").warn("End turn ignored: no open category to scratch", {
+			"player_id": player_id,
+			"category": category,
+			"function": "_handle_end_turn"
+		})
+		return
+
+	if not player_scores.has(player_id):
+		player_scores[player_id] = {}
+	player_scores[player_id][category] = 0
+	used.append(category)
+	player_used_categories[player_id] = used
+
+	_emit_event({
+		"type": "SCORE_UPDATE",
+		"player_id": player_id,
+		"category": category,
+		"score": 0,
+		"scratched": true,
+		"upper_bonus": _calculate_upper_bonus(player_id),
+		"yahtzee_bonus": yahtzee_bonuses.get(player_id, 0)
+	})
 	_advance_turn()
 
 
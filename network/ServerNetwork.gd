@@ -5,6 +5,9 @@ signal connection_state_changed(state: int, error: int)
 
 enum ConnectionState { DISCONNECTED, CONNECTING, CONNECTED, FAILED }
 
+# Wire protocol version sent on connect; must match the server's
+const PROTOCOL_VERSION := 1
+
 var _http: HTTPRequest
 var _socket: WebSocketPeer
 var _room_code: String = ""
@@ -250,21 +253,52 @@ func _on_join_completed(_result: int, response_code: int, _headers: PackedString
 	_connect_websocket(player_name)
 
 func _connect_websocket(player_name: String) -> void:
+	# Trade the token for a single-use ticket so it never appears in a URL
+	var url := "%s/rooms/%s/tickets" % [GameConfig.server_url, _room_code]
+	var body := {
+		"player_id": _player_id,
+		"token": _token
+	}
+	
+	get_node("/root/Logger").debug("Requesting connect ticket", {
+		"room_code": _room_code,
+		"player_id": _player_id,
+		"function": "_connect_websocket"
+	})
+	
+	if not _http.request_completed.is_connected(_on_ticket_completed):
+		_http.request_completed.connect(_on_ticket_completed.bind(player_name))
+	_http.request(url, ["Content-Type: application/json"], HTTPClient.METHOD_POST, JSON.stringify(body))
+
+func _on_ticket_completed(_result: int, response_code: int, _headers: PackedStringArray, body: PackedByteArray, player_name: String) -> void:
+	_http.request_completed.disconnect(_on_ticket_completed)
+	
+	var data = JSON.parse_string(body.get_string_from_utf8())
+	if response_code != 200 or typeof(data) != TYPE_DICTIONARY:
+		get_node("/root/Logger").error("Failed to get connect ticket", {
+			"response_code": response_code,
+			"result": _result,
+			"room_code": _room_code,
+			"player_id": _player_id,
+			"function": "_on_ticket_completed"
+		})
+		emit_signal("connection_state_changed", ConnectionState.FAILED, response_code)
+		return
+	
 	# Convert http:// to ws:// or https:// to wss://
 	var ws_url := GameConfig.server_url.replace("http://", "ws://").replace("https://", "wss://")
-	ws_url = "%s/rooms/%s/ws?player_id=%s&token=%s&player_name=%s" % [
+	ws_url = "%s/rooms/%s/ws?ticket=%s&protocol=%d" % [
 		ws_url, 
 		_room_code, 
-		_player_id.uri_encode(), 
-		_token.uri_encode(),
-		player_name.uri_encode()
+		str(data.get("ticket", "")).uri_encode(),
+		PROTOCOL_VERSION
 	]
 	
 	get_node("/root/Logger").debug("Connecting WebSocket", {
 		"room_code": _room_code,
 		"player_id": _player_id,
 		"player_name": player_name,
-		"function": "_connect_websocket"
+		"function": "_on_ticket_completed"
 	})
 	
 	var err := _socket.connect_to_url(ws_url)
@@ -273,7 +307,7 @@ func _connect_websocket(player_name: String) -> void:
 			"error": err,
 			"room_code": _room_code,
 			"player_id": _player_id,
-			"function": "_connect_websocket"
+			"function": "_on_ticket_completed"
 		})
 		emit_signal("connection_state_changed", ConnectionState.FAILED, err)
 
@@ -322,6 +356,18 @@ func _handle_server_message(data: Dictionary) -> void:
 				"player_id": _player_id,
 				"function": "_handle_server_message"
 			})
+		"ERROR":
+			# A command we sent was rejected
+			get_node("/root/Logger").warn("Command rejected by server", {
+				"code": str(data.get("code", "")),
+				"message": str(data.get("message", "")),
+				"command_type": str(data.get("command_type", "")),
+				"request_id": str(data.get("request_id", "")),
+				"room_code": _room_code,
+				"player_id": _player_id,
+				"function": "_handle_server_message"
+			})
+			emit_signal("game_event_received", data)
 		"VIEWER_MODE":
 			# Player is in viewer mode (rejoined after game started)
 			get_node("/root/Logger").info("Entering viewer mode", {
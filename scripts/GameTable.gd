@@ -42,6 +42,10 @@ var scorecard_panel: Node
 var _pending_game_start_event: Dictionary = {}  # Store event while showing animation
 var _intentional_leave: bool = false
 var _game_state_processed: bool = false  # Track if we've already processed GAME_STATE
+var turn_number: int = 0  # Server's turn counter, sent back with turn actions
+var roll_number: int = 0  # Rolls taken so far this turn
+var _action_seq: int = 0  # Makes each action ID unique
+var _scratch_mode: bool = false  # Next category picked is scratched for 0 to end the turn
 
 func _ready() -> void:
 	_setup_ui_styles()
@@ -264,6 +268,7 @@ func _setup_ui_styles() -> void:
 	leave_button.text = ""
 	roll_button.text = ""
 	end_turn_button.text = ""
+	end_turn_button.tooltip_text = "Scratch a category"
 
 func _style_icon_button(btn: Button, size_val: float) -> void:
 	var style := StyleBoxFlat.new()
@@ -363,6 +368,11 @@ func _on_game_event(event: Dictionary) -> void:
 		"function": "_on_game_event"
 	})
 	
+	# Remember which turn and roll the server is on
+	if event.has("turn"):
+		turn_number = int(event.get("turn", 0))
+		roll_number = int(event.get("roll", 0))
+	
 	match event_type:
 		"PLAYER_JOINED":
 			_handle_player_joined(event)
@@ -1221,6 +1231,7 @@ func _handle_turn_change(event: Dictionary) -> void:
 	_update_current_player_label()
 	rolls_left = 3
 	_update_rolls_label()
+	_scratch_mode = false
 	
 	# Clear previews from previous turn
 	scorecard_panel.clear_previews()
@@ -1398,25 +1409,47 @@ func _on_roll_pressed() -> void:
 		"player_id": local_player_id,
 		"held_indices": held_indices
 	}
-	GameNetwork.send_game_event(ev)
+	GameNetwork.send_game_event(_turn_action(ev))
 
 func _on_end_turn_pressed() -> void:
 	if GameConfig.is_viewer:
 		return
 	if not _is_local_turn():
 		return
-	# Player should have chosen a category; just send TURN_CHANGE
+	# Ending a turn means scratching a category, so the next category
+	# picked is sent as the scratch instead of being scored
+	_scratch_mode = not _scratch_mode
+	if _scratch_mode:
+		info_log.append_text("[color=#c7a88d]Pick a category to scratch for 0.[/color]\n")
+	else:
+		info_log.append_text("Scratch cancelled.\n")
+
+func _scratch_category(cat: String) -> void:
+	_scratch_mode = false
+	get_node("/root/Logger").info("Category scratched by local player", {
+		"player_id": local_player_id,
+		"category": cat,
+		"room_code": GameConfig.room_code,
+		"function": "_scratch_category"
+	})
+
 	var ev := {
 		"type": "REQUEST_END_TURN",
-		"player_id": local_player_id
+		"player_id": local_player_id,
+		"category": cat
 	}
-	GameNetwork.send_game_event(ev)
+	GameNetwork.send_game_event(_turn_action(ev))
+	scorecard_panel.set_all_interactive(false)
+	end_turn_button.disabled = true
 
 func _on_category_chosen(cat: String) -> void:
 	if GameConfig.is_viewer:
 		return
 	if not _is_local_turn():
 		return
+	if _scratch_mode:
+		_scratch_category(cat)
+		return
 	# Calculate score locally, but authority should verify as well
 	var scores: Dictionary = ScoreLogic.score_all(dice_values)
 	var score: int = int(scores.get(cat, 0))
@@ -1433,12 +1466,20 @@ func _on_category_chosen(cat: String) -> void:
 	var ev := {
 		"type": "CATEGORY_CHOSEN",
 		"player_id": local_player_id,
-		"category": cat,
-		"score": score
+		"category": cat
 	}
-	GameNetwork.send_game_event(ev)
+	GameNetwork.send_game_event(_turn_action(ev))
 	scorecard_panel.set_all_interactive(false)
-	end_turn_button.disabled = false
+	end_turn_button.disabled = true  # Scoring ends the turn
+
+# Tag a turn action with a unique ID and the turn and roll it is meant for,
+# so the server applies a double-tap once and drops actions that arrive late
+func _turn_action(ev: Dictionary) -> Dictionary:
+	_action_seq += 1
+	ev["action_id"] = "%s-%d" % [local_player_id, _action_seq]
+	ev["turn"] = turn_number
+	ev["roll"] = roll_number
+	return ev
 
 func _on_leave_pressed() -> void:
 	_intentional_leave = true
@@ -1448,6 +1489,7 @@ func _on_leave_pressed() -> void:
 func _disable_all_controls() -> void:
 	roll_button.disabled = true
 	end_turn_button.disabled = true
+	_scratch_mode = false
 	# Don't disable leave button for viewers - they should be able to exit
 	if not GameConfig.is_viewer:
 		leave_button.disabled = true
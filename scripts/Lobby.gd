@@ -460,9 +460,7 @@ func _on_start_pressed() -> void:
 	})
 
 	var start_event := {
-		"type": "GAME_START",
-		"players": players,
-		"first_player_id": players.keys()[0]
+		"type": "GAME_START"
 	}
 	GameNetwork.send_game_event(start_event)
 
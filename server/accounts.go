@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Limits on account credentials. bcrypt ignores anything past 72 bytes,
+// so longer passwords are refused rather than silently cut short.
+const (
+	minPasswordLength  = 8
+	maxPasswordLength  = 72
+	maxDisplayNameSize = 32
+)
+
+var (
+	errAccountNotFound = errors.New("account not found")
+	errAccountExists   = errors.New("account already exists")
+	errUsernameTaken   = errors.New("username is taken")
+	errNotSignedIn     = errors.New("token doesn't sign in to an account or guest identity")
+	errInvalidUsername = errors.New("username must be 3-32 letters, digits, '_' or '-'")
+	errInvalidPassword = errors.New("password must be between 8 and 72 bytes")
+	errInvalidDisplay  = errors.New("display_name is too long")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+// dummyPasswordHash is checked against when a username is unknown, so a
+// failed login takes as long whether or not the account exists
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// Account is a registered player. Seats taken while signed in are linked
+// to the account, so its games can be found again from any device.
+type Account struct {
+	ID           string    `json:"account_id"`
+	Username     string    `json:"username"`
+	DisplayName  string    `json:"display_name"`
+	PasswordHash []byte    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// identity is who a request is signed in as: an account, or a device's
+// guest identity. Seats are linked to its ID either way; a guest who
+// upgrades keeps their ID as their account's.
+type identity struct {
+	ID   string
+	Name string // Display name
+}
+
+// id returns the identity's ID, or "" when signed out
+func (who *identity) id() string {
+	if who == nil {
+		return ""
+	}
+	return who.ID
+}
+
+// usernameKey is the form usernames are compared in, so "Alice" and
+// "alice" can't both register
+func usernameKey(username string) string {
+	return strings.ToLower(username)
+}
+
+// issueAccountToken signs a session token for an account. It's the same
+// kind of token rooms use, for no room and with the account role, so it
+// can never pass as a seat in one.
+func (gm *GameManager) issueAccountToken(accountID string) string {
+	return gm.tokens.issue("", accountID, RoleAccount, gm.clock.Now())
+}
+
+// signedIn checks a request's "Authorization: Bearer" token grants one of
+// roles and isn't tied to a room
+func (gm *GameManager) signedIn(r *http.Request, roles ...string) (sessionClaims, error) {
+	claims, err := gm.tokens.verify(bearerToken(r), gm.clock.Now())
+	if err != nil {
+		return claims, err
+	}
+	if claims.RoomCode != "" || !slices.Contains(roles, claims.Role) {
+		return claims, errNotSignedIn
+	}
+	return claims, nil
+}
+
+// requestIdentity returns who a create or join request is signed in as,
+// or nil when it has no token
+func (gm *GameManager) requestIdentity(r *http.Request) (*identity, error) {
+	if bearerToken(r) == "" {
+		return nil, nil
+	}
+	claims, err := gm.signedIn(r, RoleAccount, RoleGuest)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Role == RoleGuest {
+		if err := gm.checkGuest(claims); err != nil {
+			return nil, err
+		}
+		return &identity{ID: claims.PlayerID, Name: claims.Name}, nil
+	}
+	account, err := gm.store.LoadAccount(claims.PlayerID)
+	if err != nil {
+		return nil, err
+	}
+	return &identity{ID: account.ID, Name: account.DisplayName}, nil
+}
+
+// playerName is the name a player takes in a room: the one they asked
+// for, else their display name
+func playerName(name string, who *identity) string {
+	if name == "" && who != nil {
+		name = who.Name
+	}
+	if name == "" {
+		name = "Player"
+	}
+	return name
+}
+
+// identityPlayer returns the room's player linked to an account or guest
+// identity, or nil
+func (room *Room) identityPlayer(id string) *Player {
+	for _, player := range room.Players {
+		if player.AccountID == id {
+			return player
+		}
+	}
+	return nil
+}
+
+// writeAccountSession answers with an account and a fresh session token
+func (gm *GameManager) writeAccountSession(w http.ResponseWriter, status int, account Account) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"account_id":   account.ID,
+		"username":     account.Username,
+		"display_name": account.DisplayName,
+		"token":        gm.issueAccountToken(account.ID),
+	})
+}
+
+// Register handles POST /accounts, creating an account and signing it in
+func (gm *GameManager) Register(w http.ResponseWriter, r *http.Request) {
+	gm.createAccount(w, r, generatePlayerID(), "")
+}
+
+// createAccount registers the account described by a request's body under
+// id and answers with its session. The display name falls back to
+// displayName, then to the username.
+func (gm *GameManager) createAccount(w http.ResponseWriter, r *http.Request, id, displayName string) {
+	var req struct {
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		DisplayName string `json:"display_name"` // Optional
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch {
+	case !usernamePattern.MatchString(req.Username):
+		err = errInvalidUsername
+	case len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength:
+		err = errInvalidPassword
+	case len(req.DisplayName) > maxDisplayNameSize:
+		err = errInvalidDisplay
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.DisplayName == "" {
+		req.DisplayName = displayName
+	}
+	if req.DisplayName == "" {
+		req.DisplayName = req.Username
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+	account := Account{
+		ID:           id,
+		Username:     req.Username,
+		DisplayName:  req.DisplayName,
+		PasswordHash: hash,
+		CreatedAt:    gm.clock.Now(),
+	}
+
+	err = gm.store.CreateAccount(account)
+	if errors.Is(err, errUsernameTaken) {
+		http.Error(w, "Username is taken", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errAccountExists) {
+		http.Error(w, "Account already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("username", req.Username).
+			Msg("Failed to save account")
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().
+		Str("account_id", account.ID).
+		Str("username", account.Username).
+		Msg("Account registered")
+
+	gm.writeAccountSession(w, http.StatusCreated, account)
+}
+
+// Login handles POST /accounts/login, trading a username and password for
+// a session token
+func (gm *GameManager) Login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account, err := gm.store.FindAccount(req.Username)
+	if err != nil && !errors.Is(err, errAccountNotFound) {
+		log.Error().
+			Err(err).
+			Str("username", req.Username).
+			Msg("Failed to load account")
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	hash := account.PasswordHash
+	if err != nil {
+		hash = dummyPasswordHash
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || err != nil {
+		log.Debug().
+			Str("username", req.Username).
+			Msg("Login failed")
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	gm.writeAccountSession(w, http.StatusOK, account)
+}
+
+// GetAccount handles GET /accounts/me, returning the signed-in account
+// with its finished games and stats
+func (gm *GameManager) GetAccount(w http.ResponseWriter, r *http.Request) {
+	claims, err := gm.signedIn(r, RoleAccount)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	account, err := gm.store.LoadAccount(claims.PlayerID)
+	if errors.Is(err, errAccountNotFound) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var games []string
+	var stats PlayerStats
+	if err == nil {
+		games, stats, err = gm.playerHistory(account.ID)
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("account_id", claims.PlayerID).
+			Msg("Failed to load account")
+		http.Error(w, "Failed to load account", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"account_id":   account.ID,
+		"username":     account.Username,
+		"display_name": account.DisplayName,
+		"created_at":   account.CreatedAt,
+		"games":        games,
+		"stats":        stats,
+	})
+}
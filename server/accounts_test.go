@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	srv := newTestServer(t, nil)
+	status, alice := srv.request(http.MethodPost, "/accounts", "", map[string]interface{}{
+		"username": "Alice",
+		"password": "correct horse",
+	})
+	if status != http.StatusCreated || alice["token"] == "" || alice["display_name"] != "Alice" {
+		t.Fatalf("register = %d %v, want 201 with a token", status, alice)
+	}
+
+	registers := []struct {
+		name     string
+		username string
+		password string
+		status   int
+	}{
+		{"username taken", "Alice", "another password", http.StatusConflict},
+		{"username taken in another case", "alice", "another password", http.StatusConflict},
+		{"invalid username", "a b", "correct horse", http.StatusBadRequest},
+		{"short password", "Bob", "short", http.StatusBadRequest},
+		{"long password", "Bob", strings.Repeat("x", maxPasswordLength+1), http.StatusBadRequest},
+	}
+	for _, tt := range registers {
+		t.Run("register "+tt.name, func(t *testing.T) {
+			status, _ := srv.request(http.MethodPost, "/accounts", "", map[string]interface{}{
+				"username": tt.username,
+				"password": tt.password,
+			})
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	logins := []struct {
+		name     string
+		username string
+		password string
+		status   int
+	}{
+		{"correct password", "Alice", "correct horse", http.StatusOK},
+		{"username in another case", "ALICE", "correct horse", http.StatusOK},
+		{"wrong password", "Alice", "wrong horse", http.StatusUnauthorized},
+		{"unknown username", "Mallory", "correct horse", http.StatusUnauthorized},
+	}
+	for _, tt := range logins {
+		t.Run("login "+tt.name, func(t *testing.T) {
+			status, session := srv.request(http.MethodPost, "/accounts/login", "", map[string]interface{}{
+				"username": tt.username,
+				"password": tt.password,
+			})
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if status == http.StatusOK && session["account_id"] != alice["account_id"] {
+				t.Errorf("signed in as %v, want %v", session["account_id"], alice["account_id"])
+			}
+		})
+	}
+
+	seat := srv.post("/rooms", map[string]interface{}{"player_name": "Alice"})
+	tokens := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"account token", alice["token"].(string), http.StatusOK},
+		{"no token", "", http.StatusUnauthorized},
+		{"room token", seat["token"].(string), http.StatusUnauthorized},
+	}
+	for _, tt := range tokens {
+		t.Run("me with "+tt.name, func(t *testing.T) {
+			status, me := srv.request(http.MethodGet, "/accounts/me", tt.token, nil)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if status == http.StatusOK && me["username"] != "Alice" {
+				t.Errorf("me = %v, want Alice", me)
+			}
+		})
+	}
+}
+
+// finishAccountGame plays a game between a host and a guest who have
+// filled every box but one, and returns the game's ID. The host takes 40
+// for a large straight; the guest scratches ones.
+func finishAccountGame(t *testing.T, srv *testServer, code string, host, guest map[string]interface{}) string {
+	t.Helper()
+	if errCode := srv.command(code, host, map[string]interface{}{"type": "GAME_START"}); errCode != "" {
+		t.Fatalf("GAME_START: %s", errCode)
+	}
+	room := srv.room(code)
+	room.do(func() {
+		for _, p := range room.Players {
+			for _, category := range classicCategories {
+				p.Scores[category] = 0
+			}
+		}
+		delete(room.Players[host["player_id"].(string)].Scores, "large_straight")
+		delete(room.Players[guest["player_id"].(string)].Scores, "ones")
+	})
+
+	turns := []struct {
+		player   map[string]interface{}
+		category string
+	}{
+		{host, "large_straight"},
+		{guest, "ones"},
+	}
+	for _, turn := range turns {
+		for _, event := range []map[string]interface{}{
+			{"type": "REQUEST_ROLL"},
+			{"type": "CATEGORY_CHOSEN", "category": turn.category},
+		} {
+			if errCode := srv.command(code, turn.player, event); errCode != "" {
+				t.Fatalf("%s %s: %s", turn.player["player_id"], event["type"], errCode)
+			}
+		}
+	}
+
+	var gameID string
+	room.do(func() {
+		if end, ok := lastEvent(room, "GAME_END").(*GameEndEvent); ok {
+			gameID = end.GameID
+		}
+	})
+	if gameID == "" {
+		t.Fatal("game didn't end")
+	}
+	return gameID
+}
+
+func TestAccountStatsAfterGame(t *testing.T) {
+	gm := NewGameManager(Config{}, newMemoryStore())
+	gm.dice = NewScriptedDice([]int{2, 3, 4, 5, 6})
+	srv := newTestServer(t, gm)
+
+	accounts := map[string]map[string]interface{}{}
+	for _, name := range []string{"Alice", "Bob"} {
+		status, account := srv.request(http.MethodPost, "/accounts", "", map[string]interface{}{
+			"username": name,
+			"password": "correct horse",
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("register %s = %d", name, status)
+		}
+		accounts[name] = account
+	}
+
+	_, host := srv.request(http.MethodPost, "/rooms", accounts["Alice"]["token"].(string), map[string]interface{}{})
+	code, _ := host["room_code"].(string)
+	conn, err := srv.dial(code, host, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, guest := srv.request(http.MethodPost, "/rooms/join", accounts["Bob"]["token"].(string), map[string]interface{}{"room_code": code})
+
+	// The room records who signed in, but other players never see it
+	added := conn.waitFor(t, "PLAYER_ADDED")
+	if _, shown := added["account_id"]; shown || added["player_id"] != guest["player_id"] {
+		t.Errorf("PLAYER_ADDED sent as %v, want Bob without an account ID", added)
+	}
+	var recorded string
+	room := srv.room(code)
+	room.do(func() {
+		if e, ok := lastEvent(room, "PLAYER_ADDED").(*PlayerAddedEvent); ok {
+			recorded = e.AccountID
+		}
+	})
+	if recorded != accounts["Bob"]["account_id"] {
+		t.Errorf("recorded PLAYER_ADDED account = %q, want Bob's", recorded)
+	}
+	_, polled := srv.request(http.MethodGet, fmt.Sprintf("/rooms/%s/events?protocol=%d", code, ProtocolVersion), host["token"].(string), nil)
+	if events, _ := polled["events"].([]interface{}); len(events) == 0 || strings.Contains(fmt.Sprint(events), "account_id") {
+		t.Errorf("polled events = %v, want them without account IDs", events)
+	}
+
+	gameID := finishAccountGame(t, srv, code, host, guest)
+
+	want := map[string]PlayerStats{
+		"Alice": {GamesPlayed: 1, Wins: 1, BestScore: 40},
+		"Bob":   {GamesPlayed: 1},
+	}
+	for name, stats := range want {
+		status, me := srv.request(http.MethodGet, "/accounts/me", accounts[name]["token"].(string), nil)
+		if status != http.StatusOK {
+			t.Fatalf("%s: GET /accounts/me = %d", name, status)
+		}
+		got, _ := me["stats"].(map[string]interface{})
+		if fmt.Sprint(me["games"]) != fmt.Sprint([]interface{}{gameID}) ||
+			got["games_played"] != float64(stats.GamesPlayed) || got["wins"] != float64(stats.Wins) ||
+			got["draws"] != float64(stats.Draws) || got["best_score"] != float64(stats.BestScore) {
+			t.Errorf("%s: games %v, stats %v; want [%s], %+v", name, me["games"], got, gameID, stats)
+		}
+	}
+
+	status, game := srv.request(http.MethodGet, "/games/"+gameID, "", nil)
+	if status != http.StatusOK || strings.Contains(fmt.Sprint(game["players"]), "account_id") {
+		t.Errorf("GET /games/%s = %d, players %v; want them without account IDs", gameID, status, game["players"])
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "errors"
+
+// actionHistorySize is how many recent action IDs are remembered per
+// player. A client only ever retries its latest few actions.
+const actionHistorySize = 32
+
+var (
+	errStaleTurn = errors.New("command is for a turn that isn't the current one")
+	errStaleRoll = errors.New("command is for a roll that isn't the current one")
+)
+
+// actionResult is the outcome of an action a player sent with an ID
+type actionResult struct {
+	id  string
+	err error
+}
+
+// findAction returns the earlier action with this ID, or nil
+func (p *Player) findAction(id string) *actionResult {
+	for i := range p.actions {
+		if p.actions[i].id == id {
+			return &p.actions[i]
+		}
+	}
+	return nil
+}
+
+// recordAction remembers an action's outcome, forgetting the oldest once
+// the history is full
+func (p *Player) recordAction(id string, err error) {
+	if len(p.actions) == actionHistorySize {
+		p.actions = p.actions[1:]
+	}
+	p.actions = append(p.actions, actionResult{id: id, err: err})
+}
+
+// turnPosition reports which turn and roll the room is on, for clients to
+// send back with their next command
+func (room *Room) turnPosition() TurnPosition {
+	return TurnPosition{
+		Turn: room.TurnNumber,
+		Roll: room.RollNumber,
+	}
+}
+
+// checkTarget rejects a command meant for a turn or roll that has passed
+func (room *Room) checkTarget(cmd Command) error {
+	turn, roll := cmd.target()
+	if turn != nil && *turn != room.TurnNumber {
+		return errStaleTurn
+	}
+	if roll != nil && *roll != room.RollNumber {
+		return errStaleRoll
+	}
+	return nil
+}
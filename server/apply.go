@@ -0,0 +1,151 @@
+package main
+
+import "time"
+
+// apply changes room state to match a recorded event. Every change to game
+// state goes through here, so replaying a room's events rebuilds it
+// exactly; see Room.rebuild. Handlers validate commands before recording
+// anything, so apply trusts the event. Timers and connections aren't game
+// state and are left to the caller.
+func (room *Room) apply(event ServerEvent) {
+	switch e := event.(type) {
+	case *RoomCreatedEvent:
+		room.HostID = e.HostID
+		room.applySettings(e.Settings)
+
+	case *PlayerAddedEvent:
+		room.addPlayer(e)
+
+	case *PlayerLeftEvent:
+		room.removePlayer(e.PlayerID)
+
+	case *PlayerReadyEvent:
+		if player, exists := room.Players[e.PlayerID]; exists {
+			player.Ready = e.Ready
+		}
+
+	case *SettingsEvent:
+		room.applySettings(e.Settings)
+
+	case *GameStartedEvent:
+		room.GameStarted = true
+		room.PlayerOrder = append([]string(nil), e.TurnOrder...)
+		room.FairRollCount = 0
+		for _, p := range room.Players {
+			p.Scores = make(map[string]int)
+			p.TotalScore = 0
+			p.YahtzeeBonus = 0
+			p.SavedRolls = 0
+		}
+		room.startTurn(e.CurrentPlayer, e.Turn, e.RollsLeft, e.TurnDeadline)
+
+	case *RollResultEvent:
+		// Copy the dice so later rolls don't rewrite this event's history
+		room.CurrentDice = append([]int(nil), e.Dice...)
+		room.RollsLeft = e.RollsLeft
+		room.RollNumber = e.Roll
+		room.Phase = afterRoll(e.RollsLeft)
+		if e.RollIndex != nil {
+			room.FairRollCount = *e.RollIndex
+		}
+
+	case *ScoreUpdateEvent:
+		if player, exists := room.Players[e.PlayerID]; exists {
+			player.Scores[e.Category] = e.Score
+			player.TotalScore += e.Score
+			player.YahtzeeBonus = e.YahtzeeBonus
+		}
+
+	case *TurnChangedEvent:
+		// Bank unused rolls for variants that save them
+		if e.PreviousPlayer != "" && room.Rules.SavesUnusedRolls() {
+			if player, exists := room.Players[e.PreviousPlayer]; exists {
+				player.SavedRolls = room.RollsLeft
+			}
+		}
+		room.startTurn(e.CurrentPlayer, e.Turn, e.RollsLeft, e.TurnDeadline)
+
+	case *GameEndEvent:
+		room.Phase = PhaseGameOver
+		if e.Seed != "" {
+			room.FairSeed = e.Seed
+		}
+	}
+}
+
+// addPlayer seats a new player, or updates one who rejoined. Players who
+// join as viewers never get a place in the turn order.
+func (room *Room) addPlayer(e *PlayerAddedEvent) {
+	player, exists := room.Players[e.PlayerID]
+	if !exists {
+		player = &Player{
+			ID:     e.PlayerID,
+			Scores: make(map[string]int),
+		}
+		room.Players[e.PlayerID] = player
+		if !e.IsViewer {
+			room.PlayerOrder = append(room.PlayerOrder, e.PlayerID)
+		}
+	}
+	player.Name = e.Name
+	player.IsViewer = e.IsViewer
+	if e.AccountID != "" {
+		player.AccountID = e.AccountID
+	}
+}
+
+// removePlayer takes a player out of the turn order, keeping the current
+// player's index pointing at the same player, or at the next one if it was
+// them who left. Before the game starts they leave the room entirely;
+// after, they stay so they can rejoin as a viewer.
+func (room *Room) removePlayer(playerID string) {
+	if !room.GameStarted {
+		delete(room.Players, playerID)
+	}
+
+	leavingIdx := -1
+	newOrder := make([]string, 0, len(room.PlayerOrder))
+	for i, pid := range room.PlayerOrder {
+		if pid == playerID {
+			leavingIdx = i
+			continue
+		}
+		newOrder = append(newOrder, pid)
+	}
+	room.PlayerOrder = newOrder
+
+	switch {
+	case len(room.PlayerOrder) == 0:
+		room.CurrentPlayerIdx = 0
+	case leavingIdx >= 0 && leavingIdx < room.CurrentPlayerIdx:
+		room.CurrentPlayerIdx--
+	default:
+		// The current player left, or the index ran off the end
+		room.CurrentPlayerIdx = room.CurrentPlayerIdx % len(room.PlayerOrder)
+	}
+}
+
+// startTurn begins a player's turn. rollsLeft already counts any rolls the
+// player saved from earlier turns.
+func (room *Room) startTurn(playerID string, turn, rollsLeft int, deadline *int64) {
+	room.CurrentPlayerIdx = 0
+	for i, pid := range room.PlayerOrder {
+		if pid == playerID {
+			room.CurrentPlayerIdx = i
+			break
+		}
+	}
+	if player, exists := room.Players[playerID]; exists {
+		player.SavedRolls = 0
+	}
+
+	room.TurnNumber = turn
+	room.RollNumber = 0
+	room.RollsLeft = rollsLeft
+	room.CurrentDice = newDice(room.Rules, 0)
+	room.Phase = PhaseAwaitingRoll
+	room.TurnDeadline = time.Time{}
+	if deadline != nil {
+		room.TurnDeadline = time.UnixMilli(*deadline)
+	}
+}
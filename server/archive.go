@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+var errGameNotFound = errors.New("game not found")
+
+// replayEventTypes are the recorded events that make up a game's replay
+// timeline. Chat and viewers coming and going are left out.
+var replayEventTypes = map[string]bool{
+	"GAME_STARTED": true,
+	"ROLL_RESULT":  true,
+	"SCORE_UPDATE": true,
+	"TURN_CHANGED": true,
+	"TURN_TIMEOUT": true,
+	"PLAYER_LEFT":  true,
+	"GAME_END":     true,
+}
+
+// ArchivedGame is a finished game kept for replays and disputes after its
+// room is gone
+type ArchivedGame struct {
+	ID          string                `json:"game_id"`
+	RoomCode    string                `json:"room_code"`
+	Variant     string                `json:"variant"`
+	Categories  []string              `json:"categories"`
+	Settings    RoomSettings          `json:"settings"`
+	FinishedAt  time.Time             `json:"finished_at"`
+	Players     []ArchivedPlayer      `json:"players"` // In turn order
+	HostID      string                `json:"host_id"`
+	FinalScores map[string]FinalScore `json:"final_scores"`
+	WinnerID    string                `json:"winner_id"`
+	WinnerName  string                `json:"winner_name"`
+	IsDraw      bool                  `json:"is_draw"`
+	SeedHash    string                `json:"seed_hash,omitempty"` // Provably fair games only
+	Seed        string                `json:"seed,omitempty"`
+	Timeline    []GameEvent           `json:"timeline"` // From GAME_STARTED to GAME_END
+}
+
+// ArchivedPlayer is a player who took a seat in an archived game
+type ArchivedPlayer struct {
+	PlayerID  string `json:"player_id"`
+	Name      string `json:"name"`
+	AccountID string `json:"account_id,omitempty"` // Set when they were signed in; not shown by GetGame
+}
+
+// PlayerStats sums up the finished games an account or guest played
+type PlayerStats struct {
+	GamesPlayed int `json:"games_played"`
+	Wins        int `json:"wins"`
+	Draws       int `json:"draws"`
+	BestScore   int `json:"best_score"`
+}
+
+// generateGameID creates a random ID for a finished game
+func generateGameID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// archivedGame builds the archive of the room's finished game from its
+// events. It reports false if the game hasn't ended.
+func (room *Room) archivedGame() (ArchivedGame, bool) {
+	var started *GameStartedEvent
+	var end *GameEndEvent
+	first := 0
+	for i, evt := range room.Events {
+		switch e := evt.Payload.(type) {
+		case *GameStartedEvent:
+			started, first = e, i
+		case *GameEndEvent:
+			end = e
+		}
+	}
+	if started == nil || end == nil || end.GameID == "" {
+		return ArchivedGame{}, false
+	}
+
+	game := ArchivedGame{
+		ID:          end.GameID,
+		RoomCode:    room.Code,
+		Variant:     end.Variant,
+		Categories:  end.Categories,
+		Settings:    room.Settings,
+		FinishedAt:  time.UnixMilli(end.FinishedAt),
+		Players:     make([]ArchivedPlayer, 0, len(started.TurnOrder)),
+		HostID:      room.HostID,
+		FinalScores: end.FinalScores,
+		WinnerID:    end.WinnerID,
+		WinnerName:  end.WinnerName,
+		IsDraw:      end.IsDraw,
+		SeedHash:    started.SeedHash,
+		Seed:        end.Seed,
+	}
+
+	seated := make(map[string]bool)
+	for _, pid := range started.TurnOrder {
+		seated[pid] = true
+		player := ArchivedPlayer{
+			PlayerID: pid,
+			Name:     started.Players[pid].Name,
+		}
+		if p, exists := room.Players[pid]; exists {
+			player.AccountID = p.AccountID
+		}
+		game.Players = append(game.Players, player)
+	}
+
+	for _, evt := range room.Events[first:] {
+		if !replayEventTypes[evt.Type] {
+			continue
+		}
+		// Only seated players leaving matters to the game
+		if left, ok := evt.Payload.(*PlayerLeftEvent); ok && !seated[left.PlayerID] {
+			continue
+		}
+		game.Timeline = append(game.Timeline, evt)
+	}
+	return game, true
+}
+
+// archiveGame saves the room's finished game so it outlives the room
+func (room *Room) archiveGame() {
+	if room.store == nil {
+		return
+	}
+	game, ok := room.archivedGame()
+	if !ok {
+		return
+	}
+
+	if err := room.store.ArchiveGame(game); err != nil {
+		log.Error().
+			Err(err).
+			Str("room_code", room.Code).
+			Str("game_id", game.ID).
+			Msg("Failed to archive game")
+		return
+	}
+
+	log.Info().
+		Str("room_code", room.Code).
+		Str("game_id", game.ID).
+		Int("timeline_events", len(game.Timeline)).
+		Msg("Archived game")
+}
+
+// playerHistory returns the finished games an account or guest played,
+// oldest first, with their stats
+func (gm *GameManager) playerHistory(id string) ([]string, PlayerStats, error) {
+	var stats PlayerStats
+	games, err := gm.store.AccountGames(id)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	for _, gameID := range games {
+		game, err := gm.store.LoadGame(gameID)
+		if err != nil {
+			return nil, stats, err
+		}
+		for _, p := range game.Players {
+			if p.AccountID != id {
+				continue
+			}
+			score, scored := game.FinalScores[p.PlayerID]
+			if !scored {
+				continue
+			}
+			stats.GamesPlayed++
+			stats.BestScore = max(stats.BestScore, score.FinalScore)
+
+			top := true
+			for _, other := range game.FinalScores {
+				if other.FinalScore > score.FinalScore {
+					top = false
+				}
+			}
+			switch {
+			case top && game.IsDraw:
+				stats.Draws++
+			case top:
+				stats.Wins++
+			}
+		}
+	}
+	return games, stats, nil
+}
+
+// GetGame handles GET /games/{gameID}, returning a finished game with its
+// full replay timeline
+func (gm *GameManager) GetGame(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "gameID")
+
+	game, err := gm.store.LoadGame(gameID)
+	if errors.Is(err, errGameNotFound) {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("game_id", gameID).
+			Msg("Failed to load archived game")
+		http.Error(w, "Failed to load game", http.StatusInternalServerError)
+		return
+	}
+
+	// Account IDs only link the game to players' histories
+	for i := range game.Players {
+		game.Players[i].AccountID = ""
+	}
+	json.NewEncoder(w).Encode(game)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ticketTTL is how long a WebSocket connect ticket stays valid
+const ticketTTL = 30 * time.Second
+
+// connectTicket lets one WebSocket connection in as a player. Tickets are
+// single-use and short-lived, so they're harmless in access logs.
+type connectTicket struct {
+	playerID string
+	expires  time.Time
+}
+
+var errTokenMismatch = errors.New("token is for a different room or player")
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+// issueToken signs a session token for a player in a room
+func (gm *GameManager) issueToken(roomCode, playerID string, viewer bool) string {
+	role := RolePlayer
+	if viewer {
+		role = RoleViewer
+	}
+	return gm.tokens.issue(roomCode, playerID, role, gm.clock.Now())
+}
+
+// verifyToken checks a session token for a room and, when playerID is
+// given, that it was issued to that player. It needs no room state.
+func (gm *GameManager) verifyToken(roomCode, playerID, token string) (sessionClaims, error) {
+	claims, err := gm.tokens.verify(token, gm.clock.Now())
+	if err != nil {
+		return claims, err
+	}
+	if claims.RoomCode != roomCode || (playerID != "" && claims.PlayerID != playerID) {
+		return claims, errTokenMismatch
+	}
+	return claims, nil
+}
+
+// authenticate returns the player a verified token was issued to, if
+// they're still in the room. A viewer's token never grants a seat.
+func (room *Room) authenticate(claims sessionClaims) *Player {
+	player, exists := room.Players[claims.PlayerID]
+	if !exists || (claims.Role == RoleViewer && !player.IsViewer) {
+		return nil
+	}
+	return player
+}
+
+// issueTicket creates a connect ticket for a player, dropping any that
+// expired unused
+func (room *Room) issueTicket(playerID string) string {
+	now := room.clock.Now()
+	for ticket, t := range room.tickets {
+		if now.After(t.expires) {
+			delete(room.tickets, ticket)
+		}
+	}
+
+	ticket := generateToken()
+	room.tickets[ticket] = connectTicket{
+		playerID: playerID,
+		expires:  now.Add(ticketTTL),
+	}
+	return ticket
+}
+
+// redeemTicket uses up a connect ticket and returns the player it was
+// issued to, or nil if it's unknown, used or expired
+func (room *Room) redeemTicket(ticket string) *Player {
+	t, exists := room.tickets[ticket]
+	if !exists {
+		return nil
+	}
+	delete(room.tickets, ticket)
+	if room.clock.Now().After(t.expires) {
+		return nil
+	}
+	return room.Players[t.playerID]
+}
+
+// CreateTicket handles POST /rooms/{roomCode}/tickets. It trades a
+// player's token for a ticket to open the WebSocket with, so the token
+// itself never appears in a URL.
+func (gm *GameManager) CreateTicket(w http.ResponseWriter, r *http.Request) {
+	roomCode := chi.URLParam(r, "roomCode")
+
+	var req struct {
+		PlayerID string `json:"player_id"`
+		Token    string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := gm.verifyToken(roomCode, req.PlayerID, req.Token)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("room_code", roomCode).
+			Str("player_id", req.PlayerID).
+			Msg("Ticket request unauthorized")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gm.mutex.RLock()
+	room, exists := gm.rooms[roomCode]
+	gm.mutex.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	ticket := ""
+	ok := room.do(func() {
+		if player := room.authenticate(claims); player != nil {
+			ticket = room.issueTicket(player.ID)
+		}
+	})
+	if !ok {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	if ticket == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ticket":     ticket,
+		"expires_in": int(ticketTTL / time.Second),
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyToken(t *testing.T) {
+	gm := NewGameManager(Config{}, newMemoryStore())
+	token := gm.issueToken("ROOM01", "player-1", false)
+
+	tests := []struct {
+		name     string
+		room     string
+		playerID string
+		err      error
+	}{
+		{"for the player", "ROOM01", "player-1", nil},
+		{"for any player", "ROOM01", "", nil},
+		{"another player", "ROOM01", "player-2", errTokenMismatch},
+		{"another room", "ROOM02", "player-1", errTokenMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gm.verifyToken(tt.room, tt.playerID, token); !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	// An account token isn't a seat in any room
+	if _, err := gm.verifyToken("ROOM01", "player-1", gm.issueAccountToken("player-1")); !errors.Is(err, errTokenMismatch) {
+		t.Errorf("account token for a room: err = %v, want %v", err, errTokenMismatch)
+	}
+}
+
+func TestAuthenticateViewerToken(t *testing.T) {
+	room := newRoom("ROOM01", NewSeededDice(1), realClock{}, nil)
+	defer room.close()
+	room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{PlayerID: "p1", Name: "Alice"})
+
+	if room.authenticate(sessionClaims{PlayerID: "p1", Role: RolePlayer}) == nil {
+		t.Error("player token refused")
+	}
+	if room.authenticate(sessionClaims{PlayerID: "p1", Role: RoleViewer}) != nil {
+		t.Error("viewer token granted a seat")
+	}
+	if room.authenticate(sessionClaims{PlayerID: "p2", Role: RolePlayer}) != nil {
+		t.Error("token for someone not in the room accepted")
+	}
+}
+
+func TestConnectTicket(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	room := newRoom("ROOM01", NewSeededDice(1), clock, nil)
+	defer room.close()
+	room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{PlayerID: "p1", Name: "Alice"})
+
+	ticket := room.issueTicket("p1")
+	if player := room.redeemTicket(ticket); player == nil || player.ID != "p1" {
+		t.Fatalf("redeemTicket = %v, want p1", player)
+	}
+	if room.redeemTicket(ticket) != nil {
+		t.Error("ticket redeemed twice")
+	}
+	if room.redeemTicket("made up") != nil {
+		t.Error("unknown ticket redeemed")
+	}
+
+	expired := room.issueTicket("p1")
+	clock.Advance(ticketTTL + time.Second)
+	if room.redeemTicket(expired) != nil {
+		t.Error("expired ticket redeemed")
+	}
+
+	// Issuing a ticket sweeps out ones that expired unused
+	stale := room.issueTicket("p1")
+	clock.Advance(ticketTTL + time.Second)
+	room.issueTicket("p1")
+	if _, kept := room.tickets[stale]; kept {
+		t.Error("expired ticket kept after issuing another")
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets in the bolt file. Each room's events live in a nested bucket
+// named after the room, keyed by big-endian event ID so they load in order.
+// Finished games are kept in games, keyed by game ID. Accounts are keyed
+// by ID, with usernames mapping usernameKey to account ID, and each
+// account's games get a nested bucket in account_games mapping game ID to
+// when it finished.
+var (
+	snapshotsBucket    = []byte("snapshots")
+	eventsBucket       = []byte("events")
+	gamesBucket        = []byte("games")
+	accountsBucket     = []byte("accounts")
+	usernamesBucket    = []byte("usernames")
+	accountGamesBucket = []byte("account_games")
+)
+
+// boltStore keeps rooms in an embedded bbolt database file
+type boltStore struct {
+	db *bolt.DB
+}
+
+// openBoltStore opens or creates the database at path
+func openBoltStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := [][]byte{
+			snapshotsBucket, eventsBucket, gamesBucket,
+			accountsBucket, usernamesBucket, accountGamesBucket,
+		}
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) SaveRoom(code string, events []GameEvent, snapshot *RoomState) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		eventLog, err := tx.Bucket(eventsBucket).CreateBucketIfNotExists([]byte(code))
+		if err != nil {
+			return err
+		}
+		for _, evt := range events {
+			data, err := json.Marshal(evt)
+			if err != nil {
+				return err
+			}
+			if err := eventLog.Put(eventKey(evt.ID), data); err != nil {
+				return err
+			}
+		}
+
+		if snapshot == nil {
+			return nil
+		}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(snapshotsBucket).Put([]byte(code), data)
+	})
+}
+
+func (s *boltStore) LoadRooms() ([]SavedRoom, error) {
+	var rooms []SavedRoom
+	err := s.db.View(func(tx *bolt.Tx) error {
+		snapshots := tx.Bucket(snapshotsBucket)
+		events := tx.Bucket(eventsBucket)
+		return events.ForEachBucket(func(code []byte) error {
+			saved := SavedRoom{Code: string(code)}
+			if data := snapshots.Get(code); data != nil {
+				saved.Snapshot = &RoomState{}
+				if err := json.Unmarshal(data, saved.Snapshot); err != nil {
+					return err
+				}
+			}
+
+			err := events.Bucket(code).ForEach(func(_, data []byte) error {
+				var evt GameEvent
+				if err := json.Unmarshal(data, &evt); err != nil {
+					return err
+				}
+				saved.Events = append(saved.Events, evt)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			rooms = append(rooms, saved)
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+func (s *boltStore) DeleteRoom(code string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(snapshotsBucket).Delete([]byte(code)); err != nil {
+			return err
+		}
+		err := tx.Bucket(eventsBucket).DeleteBucket([]byte(code))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+func (s *boltStore) ArchiveGame(game ArchivedGame) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(gamesBucket).Put([]byte(game.ID), data); err != nil {
+			return err
+		}
+		finished, err := game.FinishedAt.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		for _, p := range game.Players {
+			if p.AccountID == "" {
+				continue
+			}
+			played, err := tx.Bucket(accountGamesBucket).CreateBucketIfNotExists([]byte(p.AccountID))
+			if err != nil {
+				return err
+			}
+			if err := played.Put([]byte(game.ID), finished); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) LoadGame(id string) (ArchivedGame, error) {
+	var game ArchivedGame
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(gamesBucket).Get([]byte(id))
+		if data == nil {
+			return errGameNotFound
+		}
+		return json.Unmarshal(data, &game)
+	})
+	return game, err
+}
+
+func (s *boltStore) CreateAccount(account Account) error {
+	data, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		accounts := tx.Bucket(accountsBucket)
+		if accounts.Get([]byte(account.ID)) != nil {
+			return errAccountExists
+		}
+		usernames := tx.Bucket(usernamesBucket)
+		key := []byte(usernameKey(account.Username))
+		if usernames.Get(key) != nil {
+			return errUsernameTaken
+		}
+		if err := usernames.Put(key, []byte(account.ID)); err != nil {
+			return err
+		}
+		return accounts.Put([]byte(account.ID), data)
+	})
+}
+
+func (s *boltStore) LoadAccount(id string) (Account, error) {
+	var account Account
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(accountsBucket).Get([]byte(id))
+		if data == nil {
+			return errAccountNotFound
+		}
+		return json.Unmarshal(data, &account)
+	})
+	return account, err
+}
+
+func (s *boltStore) FindAccount(username string) (Account, error) {
+	var account Account
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(usernamesBucket).Get([]byte(usernameKey(username)))
+		if id == nil {
+			return errAccountNotFound
+		}
+		data := tx.Bucket(accountsBucket).Get(id)
+		if data == nil {
+			return errAccountNotFound
+		}
+		return json.Unmarshal(data, &account)
+	})
+	return account, err
+}
+
+func (s *boltStore) AccountGames(accountID string) ([]string, error) {
+	finished := make(map[string]time.Time)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		played := tx.Bucket(accountGamesBucket).Bucket([]byte(accountID))
+		if played == nil {
+			return nil
+		}
+		return played.ForEach(func(id, data []byte) error {
+			var at time.Time
+			if err := at.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			finished[string(id)] = at
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gamesByFinish(finished), nil
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+// eventKey encodes an event ID so keys sort in event order
+func eventKey(id int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
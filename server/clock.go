@@ -0,0 +1,148 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules work so tests and QA can control it
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d has elapsed
+	AfterFunc(d time.Duration, f func()) Timer
+	// NewTicker delivers the time on its channel every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a pending AfterFunc call
+type Timer interface {
+	// Stop prevents the call and reports whether it was still pending
+	Stop() bool
+}
+
+// Ticker delivers periodic ticks
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock uses the system clock
+type realClock struct{}
+
+// Now implements Clock
+func (realClock) Now() time.Time { return time.Now() }
+
+// AfterFunc implements Clock
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// NewTicker implements Clock
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+// C implements Ticker
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// ManualClock only moves when Advance is called, firing any timers and
+// ticks that fall due. It lets scripted games exercise turn timeouts and
+// room cleanup without waiting.
+type ManualClock struct {
+	now    time.Time
+	timers []*manualTimer
+	mutex  sync.Mutex
+}
+
+type manualTimer struct {
+	clock  *ManualClock
+	due    time.Time
+	period time.Duration // Non-zero for tickers
+	fire   func(now time.Time)
+	ch     chan time.Time
+}
+
+// NewManualClock creates a clock stopped at start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now implements Clock
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// AfterFunc implements Clock
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	return c.schedule(d, 0, func(time.Time) { go f() })
+}
+
+// NewTicker implements Clock
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	ch := make(chan time.Time, 1)
+	t := c.schedule(d, d, func(now time.Time) {
+		select {
+		case ch <- now:
+		default: // Drop ticks nobody is reading, like time.Ticker
+		}
+	})
+	t.ch = ch
+	return manualTicker{t}
+}
+
+func (c *ManualClock) schedule(d, period time.Duration, fire func(time.Time)) *manualTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &manualTimer{clock: c, due: c.now.Add(d), period: period, fire: fire}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d and fires everything that falls due
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	target := c.now.Add(d)
+	for {
+		sort.Slice(c.timers, func(i, j int) bool { return c.timers[i].due.Before(c.timers[j].due) })
+		if len(c.timers) == 0 || c.timers[0].due.After(target) {
+			break
+		}
+
+		t := c.timers[0]
+		c.now = t.due
+		if t.period > 0 {
+			t.due = t.due.Add(t.period)
+		} else {
+			c.timers = c.timers[1:]
+		}
+		now := c.now
+		c.mutex.Unlock()
+		t.fire(now)
+		c.mutex.Lock()
+	}
+	c.now = target
+	c.mutex.Unlock()
+}
+
+// Stop implements Timer
+func (t *manualTimer) Stop() bool {
+	c := t.clock
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type manualTicker struct{ *manualTimer }
+
+// C implements Ticker
+func (t manualTicker) C() <-chan time.Time { return t.ch }
+
+// Stop implements Ticker
+func (t manualTicker) Stop() { t.manualTimer.Stop() }
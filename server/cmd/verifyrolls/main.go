@@ -0,0 +1,181 @@
+// Command verifyrolls checks the dice of a finished provably fair game.
+//
+// It reads the game's server events, either as a JSON array, one JSON
+// object per line, or the archived game served by GET /games/{id}, from a
+// file or stdin:
+//
+//	verifyrolls game.jsonl
+//
+// The revealed seed from GAME_END must hash to the seed_hash published in
+// GAME_STARTED, every rolled die in each ROLL_RESULT must match the value
+// derived from the seed, roll_index and client_nonce, and held dice must
+// keep their previous values. Roll indices must count 1, 2, 3... with no
+// gaps or repeats, so the server can't have thrown away rolls it didn't
+// like.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"git.macco.dev/macco/yahtzee/fairdice"
+)
+
+// event holds the fields of a server event the verifier needs
+type event struct {
+	Type        string          `json:"type"`
+	Event       json.RawMessage `json:"event"` // Set when the event is wrapped in a GameEvent
+	PlayerID    string          `json:"player_id"`
+	SeedHash    string          `json:"seed_hash"`
+	Seed        string          `json:"seed"`
+	Dice        []int           `json:"dice"`
+	HeldIndices []int           `json:"held_indices"`
+	RollIndex   int             `json:"roll_index"`
+	ClientNonce string          `json:"client_nonce"`
+}
+
+func main() {
+	input := io.Reader(os.Stdin)
+	if len(os.Args) > 1 {
+		file, err := os.Open(os.Args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	events, err := readEvents(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read events:", err)
+		os.Exit(2)
+	}
+
+	rolls, problems := verify(events)
+	for _, p := range problems {
+		fmt.Println("FAIL:", p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("OK: verified %d rolls\n", rolls)
+}
+
+// archive holds the part of an archived game the verifier needs
+type archive struct {
+	Timeline []event `json:"timeline"`
+}
+
+// readEvents parses a JSON array or JSON lines of events, or an archived
+// game's timeline
+func readEvents(r io.Reader) ([]event, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []event
+	var game archive
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' && json.Unmarshal(trimmed, &game) == nil && game.Timeline != nil {
+		// A single JSON object holding a timeline is an archived game
+		events = game.Timeline
+	} else if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var e event
+			if err := json.Unmarshal(line, &e); err != nil {
+				return nil, err
+			}
+			events = append(events, e)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Unwrap stored GameEvents: {"id": 1, "type": "...", "event": {...}}
+	for i, e := range events {
+		if len(e.Event) > 0 && e.Event[0] == '{' {
+			var inner event
+			if err := json.Unmarshal(e.Event, &inner); err != nil {
+				return nil, err
+			}
+			events[i] = inner
+		}
+	}
+	return events, nil
+}
+
+// verify re-derives every roll and returns how many were checked along
+// with a description of each problem found
+func verify(events []event) (int, []string) {
+	var seedHash, seed string
+	for _, e := range events {
+		switch e.Type {
+		case "GAME_STARTED":
+			seedHash = e.SeedHash
+		case "GAME_END":
+			seed = e.Seed
+		}
+	}
+
+	switch {
+	case seedHash == "":
+		return 0, []string{"no seed_hash published in GAME_STARTED"}
+	case seed == "":
+		return 0, []string{"no seed revealed in GAME_END"}
+	case fairdice.Commitment(seed) != seedHash:
+		return 0, []string{fmt.Sprintf("seed does not match seed_hash %s", seedHash)}
+	}
+
+	var problems []string
+	var previous []int
+	rolls := 0
+	lastIndex := 0
+	for _, e := range events {
+		switch e.Type {
+		case "GAME_STARTED":
+			previous = nil
+			lastIndex = 0
+		case "TURN_CHANGED":
+			previous = nil
+		case "ROLL_RESULT":
+			rolls++
+			if e.RollIndex != lastIndex+1 {
+				problems = append(problems, fmt.Sprintf("roll %d follows roll %d, expected roll %d", e.RollIndex, lastIndex, lastIndex+1))
+			}
+			lastIndex = e.RollIndex
+			derived := fairdice.Roll(seed, e.RollIndex, e.ClientNonce, len(e.Dice), 6)
+			held := make(map[int]bool)
+			for _, i := range e.HeldIndices {
+				held[i] = true
+			}
+
+			for i, value := range e.Dice {
+				switch {
+				case !held[i] && value != derived[i]:
+					problems = append(problems, fmt.Sprintf("roll %d die %d is %d, expected %d", e.RollIndex, i, value, derived[i]))
+				case held[i] && previous != nil && i < len(previous) && value != previous[i]:
+					problems = append(problems, fmt.Sprintf("roll %d held die %d changed from %d to %d", e.RollIndex, i, previous[i], value))
+				}
+			}
+			previous = e.Dice
+		}
+	}
+	return rolls, problems
+}
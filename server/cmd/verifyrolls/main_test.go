@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"git.macco.dev/macco/yahtzee/fairdice"
+)
+
+const testSeed = "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+
+// fairGame returns the events of an honest game of three rolls: two in the
+// first turn, the second holding dice 0 and 1, and one in the next turn
+func fairGame() []event {
+	first := fairdice.Roll(testSeed, 1, "a", 5, 6)
+	second := fairdice.Roll(testSeed, 2, "", 5, 6)
+	second[0], second[1] = first[0], first[1]
+
+	return []event{
+		{Type: "GAME_STARTED", SeedHash: fairdice.Commitment(testSeed)},
+		{Type: "ROLL_RESULT", Dice: first, RollIndex: 1, ClientNonce: "a"},
+		{Type: "ROLL_RESULT", Dice: second, HeldIndices: []int{0, 1}, RollIndex: 2},
+		{Type: "TURN_CHANGED"},
+		{Type: "ROLL_RESULT", Dice: fairdice.Roll(testSeed, 3, "b", 5, 6), RollIndex: 3, ClientNonce: "b"},
+		{Type: "GAME_END", Seed: testSeed},
+	}
+}
+
+func TestVerifyAcceptsFairGame(t *testing.T) {
+	rolls, problems := verify(fairGame())
+	if len(problems) > 0 || rolls != 3 {
+		t.Errorf("verify() = %d rolls, problems %v; want 3 rolls and none", rolls, problems)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(events []event)
+		problem string
+	}{
+		{"wrong seed", func(e []event) { e[5].Seed = testSeed[1:] }, "does not match seed_hash"},
+		{"no seed", func(e []event) { e[5].Seed = "" }, "no seed revealed"},
+		{"no commitment", func(e []event) { e[0].SeedHash = "" }, "no seed_hash"},
+		{"changed die", func(e []event) { e[4].Dice[2] = e[4].Dice[2]%6 + 1 }, "roll 3 die 2"},
+		{"changed nonce", func(e []event) { e[1].ClientNonce = "z" }, "roll 1 die"},
+		{"changed held die", func(e []event) { e[2].Dice[1] = e[2].Dice[1]%6 + 1 }, "roll 2 held die 1 changed"},
+		{"skipped roll", func(e []event) { e[4].RollIndex = 4 }, "roll 4 follows roll 2, expected roll 3"},
+		{"repeated roll", func(e []event) { e[2].RollIndex = 1 }, "roll 1 follows roll 1, expected roll 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := fairGame()
+			tt.tamper(events)
+			_, problems := verify(events)
+			for _, p := range problems {
+				if strings.Contains(p, tt.problem) {
+					return
+				}
+			}
+			t.Errorf("problems %v, want one mentioning %q", problems, tt.problem)
+		})
+	}
+}
+
+func TestReadEventsUnwrapsStoredEvents(t *testing.T) {
+	input := `{"id": 1, "type": "GAME_STARTED", "event": {"type": "GAME_STARTED", "seed_hash": "abc"}}
+
+{"type": "ROLL_RESULT", "dice": [1, 2, 3, 4, 5], "roll_index": 1}
+`
+	events, err := readEvents(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].SeedHash != "abc" || events[1].RollIndex != 1 {
+		t.Errorf("readEvents() = %+v", events)
+	}
+
+	events, err = readEvents(strings.NewReader(`[{"type": "GAME_END", "seed": "s"}]`))
+	if err != nil || len(events) != 1 || events[0].Seed != "s" {
+		t.Errorf("readEvents(array) = %+v, %v", events, err)
+	}
+}
+
+// testdata/archive.json is GET /games/{id} for a one-player provably fair
+// game of 16 rolls
+func TestVerifyArchivedGame(t *testing.T) {
+	data, err := os.ReadFile("testdata/archive.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := readEvents(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolls, problems := verify(events)
+	if len(problems) > 0 || rolls != 16 {
+		t.Errorf("verify() = %d rolls, problems %v; want 16 rolls and none", rolls, problems)
+	}
+
+	// The same game with one die changed no longer verifies
+	tampered := bytes.Replace(data, []byte(`"dice":[`), []byte(`"dice":[7,`), 1)
+	if events, err = readEvents(bytes.NewReader(tampered)); err != nil {
+		t.Fatal(err)
+	}
+	if _, problems := verify(events); len(problems) == 0 {
+		t.Error("verify() accepted an archive with a changed roll")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Config holds server settings read from the environment
+type Config struct {
+	// TurnTimeout is how long a player has to finish a turn; zero disables it
+	TurnTimeout time.Duration
+	// DiceSeed makes dice deterministic for QA; nil uses crypto randomness
+	DiceSeed *int64
+	// DiceScript replays fixed dice values for QA when non-empty
+	DiceScript []int
+	// HeartbeatTimeout is how long a WebSocket may go without answering a
+	// ping before it's treated as disconnected; zero disables heartbeats
+	HeartbeatTimeout time.Duration
+	// ReconnectGrace is how long a player whose WebSocket dropped keeps
+	// their seat, so they can reconnect and resume; zero drops them at once
+	ReconnectGrace time.Duration
+	// TokenKey signs session tokens; empty uses a random key, so tokens
+	// stop working when the server restarts
+	TokenKey []byte
+	// TokenTTL is how long a session token stays valid
+	TokenTTL time.Duration
+	// GuestTTL is how long a guest credential stays valid
+	GuestTTL time.Duration
+	// StorePath is the database file rooms are saved to; empty keeps them
+	// in memory only
+	StorePath string
+}
+
+// LoadConfig reads server settings from environment variables
+func LoadConfig() Config {
+	return Config{
+		TurnTimeout:      clampTurnTimeout(envDuration("TURN_TIMEOUT", 2*time.Minute)),
+		DiceSeed:         envInt64("DICE_SEED"),
+		DiceScript:       envInts("DICE_SCRIPT"),
+		HeartbeatTimeout: envDuration("HEARTBEAT_TIMEOUT", 60*time.Second),
+		ReconnectGrace:   envDuration("RECONNECT_GRACE", 2*time.Minute),
+		TokenKey:         []byte(os.Getenv("TOKEN_SIGNING_KEY")),
+		TokenTTL:         envDuration("TOKEN_TTL", defaultTokenTTL),
+		GuestTTL:         envDuration("GUEST_TOKEN_TTL", defaultGuestTTL),
+		StorePath:        os.Getenv("STORE_PATH"),
+	}
+}
+
+// envDuration parses a duration such as "90s" from an environment variable
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Warn().
+			Str("key", key).
+			Str("value", value).
+			Dur("default", fallback).
+			Msg("Invalid duration in environment, using default")
+		return fallback
+	}
+	return d
+}
+
+// clampTurnTimeout keeps the default turn timer within what room settings
+// allow, so rooms created without a timer of their own still validate
+func clampTurnTimeout(d time.Duration) time.Duration {
+	if d == 0 {
+		return 0
+	}
+	clamped := min(max(d, minTurnTimerSeconds*time.Second), maxTurnTimerSeconds*time.Second)
+	if clamped != d {
+		log.Warn().
+			Dur("turn_timeout", d).
+			Dur("clamped", clamped).
+			Msg("TURN_TIMEOUT out of range, clamping")
+	}
+	return clamped
+}
+
+// envInt64 parses an optional integer environment variable
+func envInt64(key string) *int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Warn().
+			Str("key", key).
+			Str("value", value).
+			Msg("Invalid integer in environment, ignoring")
+		return nil
+	}
+	return &n
+}
+
+// envInts parses a comma-separated list of integers such as "6,6,6,6,6"
+func envInts(key string) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var values []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			log.Warn().
+				Str("key", key).
+				Str("value", value).
+				Msg("Invalid integer list in environment, ignoring")
+			return nil
+		}
+		values = append(values, n)
+	}
+	return values
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Limits on outbound WebSocket traffic
+const (
+	sendQueueSize = 256              // Messages a client may fall behind by before it's dropped
+	writeWait     = 10 * time.Second // Time allowed to write a message to a client
+)
+
+// clientConn is a player's WebSocket connection. Outbound messages are
+// queued and written by a dedicated goroutine, so a slow or stalled client
+// can't hold up the room's event loop.
+type clientConn struct {
+	ws        *websocket.Conn
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	pongWait  time.Duration // Zero disables pings and the read deadline
+}
+
+// newClientConn wraps an upgraded WebSocket and starts its writer. With a
+// pongWait the writer pings the client, and reads fail once a pong is
+// overdue so dead connections go through the normal disconnect path. It
+// must be called before the connection is read from.
+func newClientConn(ws *websocket.Conn, pongWait time.Duration) *clientConn {
+	c := &clientConn{
+		ws:       ws,
+		send:     make(chan []byte, sendQueueSize),
+		done:     make(chan struct{}),
+		pongWait: pongWait,
+	}
+	if pongWait > 0 {
+		ws.SetReadDeadline(time.Now().Add(pongWait))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+	go c.writeLoop()
+	return c
+}
+
+// enqueue queues a message for the client without blocking. It returns
+// false if the client is closed or its queue is full; a full queue drops
+// the connection at once, without flushing.
+func (c *clientConn) enqueue(data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		c.close()
+		c.ws.Close() // Safe alongside a write; it unblocks the writer
+		return false
+	}
+}
+
+// close stops the client once its queued messages have been flushed. The
+// reader sees the socket close and runs the normal disconnect path.
+func (c *clientConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// writeLoop writes queued messages and pings until the client is closed
+// or a write fails
+func (c *clientConn) writeLoop() {
+	defer c.ws.Close()
+
+	// Ping often enough that a pong arrives before the read deadline
+	var ping <-chan time.Time
+	if c.pongWait > 0 {
+		ticker := time.NewTicker(c.pongWait * 9 / 10)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(data); err != nil {
+				c.close()
+				return
+			}
+		case <-ping:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			c.flush()
+			return
+		}
+	}
+}
+
+// flush writes whatever is still queued, giving up after one write deadline
+func (c *clientConn) flush() {
+	c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		default:
+			c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+// write sends one message with a deadline
+func (c *clientConn) write(data []byte) error {
+	c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.ws.WriteMessage(websocket.TextMessage, data)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/rand"
+	"math/big"
+	mrand "math/rand"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DiceSource produces dice values and random orderings for a room
+type DiceSource interface {
+	// Roll returns a value from 1 to faces
+	Roll(faces int) int
+	// Shuffle randomly permutes n elements using swap
+	Shuffle(n int, swap func(i, j int))
+}
+
+// newDiceSource picks the dice source configured for the server
+func newDiceSource(config Config) DiceSource {
+	switch {
+	case len(config.DiceScript) > 0:
+		log.Warn().
+			Ints("script", config.DiceScript).
+			Msg("Using scripted dice, rolls are not random")
+		return NewScriptedDice(config.DiceScript)
+	case config.DiceSeed != nil:
+		log.Warn().
+			Int64("seed", *config.DiceSeed).
+			Msg("Using seeded dice, rolls are reproducible")
+		return NewSeededDice(*config.DiceSeed)
+	}
+	return CryptoDice{}
+}
+
+// shuffleWith runs a Fisher-Yates shuffle drawing from roll
+func shuffleWith(roll func(faces int) int, n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, roll(i+1)-1)
+	}
+}
+
+// CryptoDice draws from crypto/rand and is used in production
+type CryptoDice struct{}
+
+// Roll implements DiceSource
+func (CryptoDice) Roll(faces int) int {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(faces)))
+	if err != nil {
+		// crypto/rand only fails if the OS entropy source is broken
+		panic(err)
+	}
+	return int(n.Int64()) + 1
+}
+
+// Shuffle implements DiceSource
+func (d CryptoDice) Shuffle(n int, swap func(i, j int)) {
+	shuffleWith(d.Roll, n, swap)
+}
+
+// SeededDice is a deterministic source for reproducing games in QA. Every
+// game played from the same seed in the same order gets the same dice.
+type SeededDice struct {
+	rng   *mrand.Rand
+	mutex sync.Mutex
+}
+
+// NewSeededDice creates a deterministic source from a seed
+func NewSeededDice(seed int64) *SeededDice {
+	return &SeededDice{rng: mrand.New(mrand.NewSource(seed))}
+}
+
+// Roll implements DiceSource
+func (d *SeededDice) Roll(faces int) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.rng.Intn(faces) + 1
+}
+
+// Shuffle implements DiceSource
+func (d *SeededDice) Shuffle(n int, swap func(i, j int)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.rng.Shuffle(n, swap)
+}
+
+// ScriptedDice replays a fixed list of values so QA can set up exact
+// scenarios. Values are used in order and repeat once the script runs out.
+// Shuffle keeps the original order so turn order follows join order.
+type ScriptedDice struct {
+	values []int
+	next   int
+	mutex  sync.Mutex
+}
+
+// NewScriptedDice creates a source that returns values in order
+func NewScriptedDice(values []int) *ScriptedDice {
+	return &ScriptedDice{values: values}
+}
+
+// Roll implements DiceSource
+func (d *ScriptedDice) Roll(faces int) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.values) == 0 {
+		return 1
+	}
+	value := d.values[d.next%len(d.values)]
+	d.next++
+	if value < 1 || value > faces {
+		value = (value-1+faces)%faces + 1
+	}
+	return value
+}
+
+// Shuffle implements DiceSource
+func (d *ScriptedDice) Shuffle(n int, swap func(i, j int)) {}
@@ -0,0 +1,95 @@
+package main
+
+import "errors"
+
+// Error codes sent to clients in ERROR events. They are part of the wire
+// protocol: never change an existing code, only add new ones.
+const (
+	CodeInvalidMessage    = "invalid_message"
+	CodeUnknownCommand    = "unknown_command"
+	CodeViewerReadOnly    = "viewer_read_only"
+	CodeNotHost           = "not_host"
+	CodeGameNotStarted    = "game_not_started"
+	CodeGameStarted       = "game_already_started"
+	CodeGameOver          = "game_over"
+	CodeNotEnoughPlayers  = "not_enough_players"
+	CodeNotYourTurn       = "not_your_turn"
+	CodeMustRollFirst     = "must_roll_first"
+	CodeNoRollsLeft       = "no_rolls_left"
+	CodeInvalidTransition = "invalid_transition"
+	CodeCategoryRequired  = "category_required"
+	CodeUnknownCategory   = "unknown_category"
+	CodeCategoryTaken     = "category_taken"
+	CodeJokerUpper        = "joker_upper_required"
+	CodeJokerLower        = "joker_lower_required"
+	CodeNonceTooLong      = "nonce_too_long"
+	CodeStaleTurn         = "stale_turn"
+	CodeStaleRoll         = "stale_roll"
+	CodeSettingsLocked    = "settings_locked"
+	CodeInvalidSettings   = "invalid_settings"
+	CodeInternal          = "internal_error"
+)
+
+var errViewerReadOnly = errors.New("viewers can't take actions")
+
+// errorCodes maps the errors a command can fail with to their codes
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{errInvalidMessage, CodeInvalidMessage},
+	{errUnknownCommand, CodeUnknownCommand},
+	{errViewerReadOnly, CodeViewerReadOnly},
+	{errNotHost, CodeNotHost},
+	{errGameNotStarted, CodeGameNotStarted},
+	{errGameAlreadyStarted, CodeGameStarted},
+	{errGameOver, CodeGameOver},
+	{errNotEnoughPlayers, CodeNotEnoughPlayers},
+	{errNotYourTurn, CodeNotYourTurn},
+	{errMustRollFirst, CodeMustRollFirst},
+	{errNoRollsLeft, CodeNoRollsLeft},
+	{errInvalidTransition, CodeInvalidTransition},
+	{errCategoryRequired, CodeCategoryRequired},
+	{errUnknownCategory, CodeUnknownCategory},
+	{errCategoryTaken, CodeCategoryTaken},
+	{errJokerUpperRequired, CodeJokerUpper},
+	{errJokerLowerRequired, CodeJokerLower},
+	{errNonceTooLong, CodeNonceTooLong},
+	{errStaleTurn, CodeStaleTurn},
+	{errStaleRoll, CodeStaleRoll},
+	{errSettingsLocked, CodeSettingsLocked},
+	{errSettingsRequired, CodeInvalidSettings},
+	{errInvalidSettings, CodeInvalidSettings},
+}
+
+// errorCode returns the stable code for an error a command failed with
+func errorCode(err error) string {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return CodeInternal
+}
+
+// commandResult builds the reply to a command: an ERROR explaining why it
+// was rejected, or an ACK if the client gave a request_id to acknowledge.
+// It returns a nil event when there's nothing to send.
+func commandResult(commandType, requestID string, err error, lastEventID int) (string, ServerEvent) {
+	if err == nil && requestID == "" {
+		return "", nil
+	}
+	if err != nil {
+		return "ERROR", &ErrorEvent{
+			Code:        errorCode(err),
+			Message:     err.Error(),
+			RequestID:   requestID,
+			CommandType: commandType,
+		}
+	}
+	return "ACK", &AckEvent{
+		RequestID:   requestID,
+		CommandType: commandType,
+		LastEventID: lastEventID,
+	}
+}
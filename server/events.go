@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ServerEvent is a message the server sends to clients. Every event
+// embeds eventHeader; the type and event ID are stamped when it's sent.
+type ServerEvent interface {
+	header() *eventHeader
+}
+
+// eventHeader holds the fields every server event carries
+type eventHeader struct {
+	Type    string `json:"type"`
+	EventID int    `json:"event_id,omitempty"` // Set on events recorded in the room history
+}
+
+func (h *eventHeader) header() *eventHeader { return h }
+
+// TurnTiming lets clients show a countdown that doesn't depend on their
+// own clock. Times are Unix milliseconds.
+type TurnTiming struct {
+	ServerTime         int64  `json:"server_time"`
+	TurnTimeoutSeconds int    `json:"turn_timeout_seconds"`
+	TurnDeadline       *int64 `json:"turn_deadline"` // nil when turns aren't timed
+}
+
+// TurnPosition identifies the turn and roll the room is on. Clients echo
+// them back as turn and roll so a late command can't land on a later turn.
+type TurnPosition struct {
+	Turn int `json:"turn"`
+	Roll int `json:"roll"` // Rolls taken so far this turn
+}
+
+// PlayerSummary describes a player in GAME_STARTED and GAME_STATE
+type PlayerSummary struct {
+	PlayerID   string         `json:"player_id"`
+	Name       string         `json:"name"`
+	Ready      bool           `json:"ready"`
+	TotalScore int            `json:"total_score"`
+	Scores     map[string]int `json:"scores"`
+	IsViewer   bool           `json:"is_viewer,omitempty"`
+}
+
+// FinalScore is one player's result in GAME_END
+type FinalScore struct {
+	Name         string `json:"name"`
+	BaseScore    int    `json:"base_score"`
+	UpperBonus   int    `json:"upper_bonus"`
+	YahtzeeBonus int    `json:"yahtzee_bonus"`
+	FinalScore   int    `json:"final_score"`
+}
+
+// RoomCreatedEvent opens a room's history (ROOM_CREATED)
+type RoomCreatedEvent struct {
+	eventHeader
+	HostID   string       `json:"host_id"`
+	Settings RoomSettings `json:"settings"`
+}
+
+// PlayerAddedEvent records a player taking a place in the room, or
+// rejoining it (PLAYER_ADDED). A player who rejoins after leaving a game
+// in progress comes back as a viewer.
+type PlayerAddedEvent struct {
+	eventHeader
+	PlayerID  string `json:"player_id"`
+	Name      string `json:"name"`
+	IsViewer  bool   `json:"is_viewer"`
+	AccountID string `json:"account_id,omitempty"` // Stored only; see clientEvent
+}
+
+// PlayerJoinedEvent announces a player in the room (PLAYER_JOINED)
+type PlayerJoinedEvent struct {
+	eventHeader
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	IsHost   bool   `json:"is_host"`
+	IsViewer bool   `json:"is_viewer"`
+}
+
+// PlayerLeftEvent announces a player leaving (PLAYER_LEFT)
+type PlayerLeftEvent struct {
+	eventHeader
+	PlayerID   string `json:"player_id"`
+	PlayerName string `json:"player_name"`
+	IsHost     bool   `json:"is_host"`
+}
+
+// PlayerReadyEvent reports a player's ready flag (PLAYER_READY)
+type PlayerReadyEvent struct {
+	eventHeader
+	PlayerID string `json:"player_id"`
+	Ready    bool   `json:"ready"`
+}
+
+// ViewerModeEvent tells a player they can only watch (VIEWER_MODE)
+type ViewerModeEvent struct {
+	eventHeader
+	PlayerID string `json:"player_id"`
+	Message  string `json:"message"`
+}
+
+// GameStateEvent brings a viewer up to date with a game in progress
+// (GAME_STATE)
+type GameStateEvent struct {
+	eventHeader
+	TurnPosition
+	Players       map[string]PlayerSummary `json:"players"`
+	PlayerList    []PlayerSummary          `json:"player_list"`
+	TurnOrder     []string                 `json:"turn_order"`
+	CurrentPlayer string                   `json:"current_player"`
+	Dice          []int                    `json:"dice"`
+	RollsLeft     int                      `json:"rolls_left"`
+	Phase         string                   `json:"phase"`
+	LastEventID   int                      `json:"last_event_id"`
+	EventHistory  []ServerEvent            `json:"event_history,omitempty"` // Left out when the client resumes
+}
+
+// SettingsEvent carries room settings (ROOM_SETTINGS, ROOM_SETTINGS_UPDATE)
+type SettingsEvent struct {
+	eventHeader
+	Settings   RoomSettings `json:"settings"`
+	Categories []string     `json:"categories"`
+}
+
+// GameStartedEvent starts the game (GAME_STARTED)
+type GameStartedEvent struct {
+	eventHeader
+	TurnTiming
+	TurnPosition
+	Players       map[string]PlayerSummary `json:"players"`
+	PlayerList    []PlayerSummary          `json:"player_list"`
+	TurnOrder     []string                 `json:"turn_order"`
+	CurrentPlayer string                   `json:"current_player"`
+	RollsLeft     int                      `json:"rolls_left"`
+	Phase         string                   `json:"phase"`
+	Variant       string                   `json:"variant"`
+	Categories    []string                 `json:"categories"`
+	ProvablyFair  bool                     `json:"provably_fair"`
+	SeedHash      string                   `json:"seed_hash,omitempty"`
+}
+
+// RollResultEvent reports a roll (ROLL_RESULT)
+type RollResultEvent struct {
+	eventHeader
+	TurnPosition
+	PlayerID    string   `json:"player_id"`
+	Dice        []int    `json:"dice"`
+	HeldIndices []int    `json:"held_indices"`
+	RollsLeft   int      `json:"rolls_left"`
+	Phase       string   `json:"phase"`
+	Variant     string   `json:"variant"`
+	Categories  []string `json:"categories"`
+	RollIndex   *int     `json:"roll_index,omitempty"`   // Provably fair rooms only
+	ClientNonce *string  `json:"client_nonce,omitempty"` // Provably fair rooms only
+}
+
+// ScoreUpdateEvent reports a filled category (SCORE_UPDATE)
+type ScoreUpdateEvent struct {
+	eventHeader
+	PlayerID     string `json:"player_id"`
+	Category     string `json:"category"`
+	Score        int    `json:"score"`
+	Bonus        int    `json:"bonus"`
+	YahtzeeBonus int    `json:"yahtzee_bonus"`
+	Scratched    bool   `json:"scratched"`
+}
+
+// TurnChangedEvent starts the next turn (TURN_CHANGED)
+type TurnChangedEvent struct {
+	eventHeader
+	TurnTiming
+	TurnPosition
+	PreviousPlayer string `json:"previous_player,omitempty"` // Banks unused rolls; empty when they left
+	CurrentPlayer  string `json:"current_player"`
+	RollsLeft      int    `json:"rolls_left"`
+	Phase          string `json:"phase"`
+}
+
+// TurnTimeoutEvent reports a turn the server is playing out (TURN_TIMEOUT)
+type TurnTimeoutEvent struct {
+	eventHeader
+	PlayerID string `json:"player_id"`
+}
+
+// GameEndEvent reports the final scores (GAME_END)
+type GameEndEvent struct {
+	eventHeader
+	Variant     string                `json:"variant"`
+	Categories  []string              `json:"categories"`
+	FinalScores map[string]FinalScore `json:"final_scores"`
+	WinnerID    string                `json:"winner_id"`
+	WinnerName  string                `json:"winner_name"`
+	IsDraw      bool                  `json:"is_draw"`
+	Seed        string                `json:"seed,omitempty"` // Revealed in provably fair rooms
+	GameID      string                `json:"game_id"`        // For fetching the replay from GET /games/{id}
+	FinishedAt  int64                 `json:"finished_at"`    // Unix milliseconds
+}
+
+// RoomEndedEvent closes the room (ROOM_ENDED)
+type RoomEndedEvent struct {
+	eventHeader
+	Reason string `json:"reason"`
+}
+
+// ChatMessageEvent relays a chat message (CHAT_MESSAGE)
+type ChatMessageEvent struct {
+	eventHeader
+	PlayerID   string `json:"player_id"`
+	PlayerName string `json:"player_name"`
+	Message    string `json:"message"`
+}
+
+// ErrorEvent tells a client why its command was rejected (ERROR). Code is
+// one of the Code constants in errors.go.
+type ErrorEvent struct {
+	eventHeader
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	RequestID   string `json:"request_id,omitempty"`
+	CommandType string `json:"command_type,omitempty"`
+}
+
+// AckEvent confirms a command carrying a request_id was applied (ACK)
+type AckEvent struct {
+	eventHeader
+	RequestID   string `json:"request_id"`
+	CommandType string `json:"command_type"`
+	LastEventID int    `json:"last_event_id"`
+}
+
+// newServerEvent returns an empty event for a type that can appear in a
+// room's history
+func newServerEvent(eventType string) (ServerEvent, bool) {
+	switch eventType {
+	case "ROOM_CREATED":
+		return &RoomCreatedEvent{}, true
+	case "PLAYER_ADDED":
+		return &PlayerAddedEvent{}, true
+	case "PLAYER_JOINED":
+		return &PlayerJoinedEvent{}, true
+	case "PLAYER_LEFT":
+		return &PlayerLeftEvent{}, true
+	case "PLAYER_READY":
+		return &PlayerReadyEvent{}, true
+	case "ROOM_SETTINGS", "ROOM_SETTINGS_UPDATE":
+		return &SettingsEvent{}, true
+	case "GAME_STARTED":
+		return &GameStartedEvent{}, true
+	case "ROLL_RESULT":
+		return &RollResultEvent{}, true
+	case "SCORE_UPDATE":
+		return &ScoreUpdateEvent{}, true
+	case "TURN_CHANGED":
+		return &TurnChangedEvent{}, true
+	case "TURN_TIMEOUT":
+		return &TurnTimeoutEvent{}, true
+	case "GAME_END":
+		return &GameEndEvent{}, true
+	case "ROOM_ENDED":
+		return &RoomEndedEvent{}, true
+	case "CHAT_MESSAGE":
+		return &ChatMessageEvent{}, true
+	}
+	return nil, false
+}
+
+// UnmarshalJSON decodes a recorded event, using its type to pick the
+// payload struct
+func (e *GameEvent) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ID      int             `json:"id"`
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"event"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	payload, ok := newServerEvent(raw.Type)
+	if !ok {
+		return fmt.Errorf("unknown event type %q", raw.Type)
+	}
+	if err := json.Unmarshal(raw.Payload, payload); err != nil {
+		return err
+	}
+	*e = GameEvent{ID: raw.ID, Type: raw.Type, Payload: payload}
+	return nil
+}
+
+// clientEvent returns an event as clients see it. Account IDs stay in the
+// room's log but aren't sent to other players.
+func clientEvent(event ServerEvent) ServerEvent {
+	if added, ok := event.(*PlayerAddedEvent); ok && added.AccountID != "" {
+		public := *added
+		public.AccountID = ""
+		return &public
+	}
+	return event
+}
+
+// clientEvents returns recorded events as clients see them
+func clientEvents(events []GameEvent) []GameEvent {
+	public := make([]GameEvent, len(events))
+	for i, evt := range events {
+		public[i] = GameEvent{ID: evt.ID, Type: evt.Type, Payload: clientEvent(evt.Payload)}
+	}
+	return public
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+
+	"git.macco.dev/macco/yahtzee/fairdice"
+)
+
+// maxClientNonceLength bounds the nonce a client may mix into a fair roll
+const maxClientNonceLength = 64
+
+var errNonceTooLong = errors.New("client_nonce is too long")
+
+// startFairDice picks a fresh secret seed for a provably fair game and
+// returns the commitment to publish before any dice are rolled. The seed
+// is the one piece of game state no event records until GAME_END reveals
+// it, so it's kept in snapshots instead; see Room.persist.
+func (room *Room) startFairDice() (string, error) {
+	seed, err := fairdice.NewSeed()
+	if err != nil {
+		return "", err
+	}
+	room.FairSeed = seed
+	return fairdice.Commitment(seed), nil
+}
+
+// fairRoll derives the next roll from the room's secret seed. It returns
+// a value for every die along with the roll counter used, which the roll's
+// event records.
+func (room *Room) fairRoll(nonce string) ([]int, int) {
+	index := room.FairRollCount + 1
+	return fairdice.Roll(room.FairSeed, index, nonce, len(room.CurrentDice), 6), index
+}
@@ -0,0 +1,54 @@
+// Package fairdice derives dice rolls from a secret seed so that a finished
+// game can be checked by anyone once the seed is revealed.
+//
+// Before the game the server publishes Commitment(seed). Each roll is
+// derived from the seed, a roll counter and an optional client nonce, so the
+// server can't change outcomes after committing and clients can influence
+// rolls without being able to predict them. After the game the seed is
+// revealed and every roll can be re-derived with Roll.
+package fairdice
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewSeed returns a random 256-bit seed encoded as hex
+func NewSeed() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Commitment returns the SHA-256 hash of the seed string, hex encoded
+func Commitment(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// Roll derives count dice with the given number of faces for roll number
+// counter. The value for die i only depends on the seed, counter, nonce and
+// i, so held dice can simply be skipped when applying a roll.
+func Roll(seed string, counter int, nonce string, count, faces int) []int {
+	dice := make([]int, count)
+	limit := 256 - 256%faces // Reject bytes that would bias the result
+
+	for i := range dice {
+		for block := 0; dice[i] == 0; block++ {
+			mac := hmac.New(sha256.New, []byte(seed))
+			fmt.Fprintf(mac, "%d:%s:%d:%d", counter, nonce, i, block)
+			for _, b := range mac.Sum(nil) {
+				if int(b) < limit {
+					dice[i] = int(b)%faces + 1
+					break
+				}
+			}
+		}
+	}
+	return dice
+}
@@ -0,0 +1,76 @@
+package fairdice
+
+import (
+	"slices"
+	"testing"
+)
+
+const testSeed = "4f1c2b9e0d7a6c5b8e3f2a1d0c9b8a7f6e5d4c3b2a190817161514131211100f"
+
+func TestRollIsDeterministic(t *testing.T) {
+	first := Roll(testSeed, 1, "nonce", 5, 6)
+	if again := Roll(testSeed, 1, "nonce", 5, 6); !slices.Equal(first, again) {
+		t.Fatalf("Roll gave %v then %v for the same inputs", first, again)
+	}
+
+	// Every input changes the dice, checked across a few rolls so a chance
+	// repeat of five dice can't fail the test
+	differs := func(roll func(counter int) []int) bool {
+		for counter := 1; counter <= 4; counter++ {
+			if !slices.Equal(roll(counter), Roll(testSeed, counter, "nonce", 5, 6)) {
+				return true
+			}
+		}
+		return false
+	}
+	if !differs(func(c int) []int { return Roll(testSeed, c+1, "nonce", 5, 6) }) {
+		t.Error("roll counter doesn't change the dice")
+	}
+	if !differs(func(c int) []int { return Roll(testSeed, c, "other", 5, 6) }) {
+		t.Error("client nonce doesn't change the dice")
+	}
+	if !differs(func(c int) []int { return Roll(testSeed[1:], c, "nonce", 5, 6) }) {
+		t.Error("seed doesn't change the dice")
+	}
+}
+
+func TestRollRange(t *testing.T) {
+	seen := make(map[int]bool)
+	for counter := 1; counter <= 200; counter++ {
+		for _, value := range Roll(testSeed, counter, "", 6, 6) {
+			if value < 1 || value > 6 {
+				t.Fatalf("roll %d has die %d, want 1-6", counter, value)
+			}
+			seen[value] = true
+		}
+	}
+	if len(seen) != 6 {
+		t.Errorf("1200 dice only showed faces %v", seen)
+	}
+}
+
+func TestRollDieDependsOnlyOnIndex(t *testing.T) {
+	five := Roll(testSeed, 7, "nonce", 5, 6)
+	six := Roll(testSeed, 7, "nonce", 6, 6)
+	if !slices.Equal(five, six[:5]) {
+		t.Errorf("five dice %v aren't the first of six %v", five, six)
+	}
+}
+
+func TestCommitment(t *testing.T) {
+	// SHA-256 of the empty string
+	if got := Commitment(""); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("Commitment(\"\") = %s", got)
+	}
+	if Commitment(testSeed) == Commitment(testSeed[1:]) {
+		t.Error("different seeds share a commitment")
+	}
+
+	seed, err := NewSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seed) != 64 {
+		t.Errorf("NewSeed() = %q, want 64 hex digits", seed)
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+// Game records are a compact text notation for a whole game, in the
+// spirit of chess PGN. Tags come first, then one line per turn:
+//
+//	[Game "c46817a6959693ceb88bf6e30c19863f"]
+//	[Date "2026.10.16"]
+//	[Variant "yahtzee"]
+//	[Player1 "Alice"]
+//	[Player2 "Bob"]
+//	[Host "P1"]
+//	[Result "231-187"]
+//
+//	1. P1 31452 3[1]4[5]2 3[1]6[5]6 full_house:25
+//	2. P2 timeout 24561 ones:1
+//	3. P1 66666 yahtzee:50
+//	4. P2 11234 P1-left 1[1]55[3]5 ones:x
+//
+// Players are seats P1, P2... in turn order. Each roll lists every die,
+// with the dice held from the previous roll in brackets. A turn ends with
+// the category filled and its score, plus "+bonus" for a Yahtzee bonus,
+// or "x" when it was scratched. "timeout" marks where the server played
+// the turn out, and "P1-left" where a player left. Result lists final
+// scores in seat order.
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// maxRecordSize bounds a game record sent for import
+const maxRecordSize = 1 << 20
+
+var (
+	errRecordSyntax     = errors.New("malformed game record")
+	errRecordMismatch   = errors.New("game record doesn't match the rules")
+	errRecordIncomplete = errors.New("game record ends before the game does")
+)
+
+var (
+	recordTagPattern  = regexp.MustCompile(`^\[(\w+) (".*")\]$`)
+	recordTurnPattern = regexp.MustCompile(`^(\d+)\.$`)
+	recordSeatPattern = regexp.MustCompile(`^P(\d+)$`)
+)
+
+// gameRecord is a parsed game record
+type gameRecord struct {
+	tags    map[string]string
+	players []string // Names, in turn order
+	turns   []recordTurn
+}
+
+// recordTurn is one turn line of a game record
+type recordTurn struct {
+	line   int
+	number int
+	seat   string
+	moves  []string
+}
+
+// recordDice rolls the dice a game record says were rolled
+type recordDice struct {
+	values []int
+}
+
+// Roll implements DiceSource
+func (d *recordDice) Roll(faces int) int {
+	if len(d.values) == 0 {
+		return 1
+	}
+	value := d.values[0]
+	d.values = d.values[1:]
+	return value
+}
+
+// Shuffle implements DiceSource, keeping seats in record order
+func (d *recordDice) Shuffle(n int, swap func(i, j int)) {}
+
+// seatName returns the record name of the player at a turn-order index
+func seatName(i int) string {
+	return "P" + strconv.Itoa(i+1)
+}
+
+// exportRecord writes an archived game as a game record
+func exportRecord(game ArchivedGame) string {
+	var b strings.Builder
+	tag := func(name, value string) {
+		fmt.Fprintf(&b, "[%s %q]\n", name, value)
+	}
+
+	tag("Game", game.ID)
+	tag("Room", game.RoomCode)
+	tag("Date", game.FinishedAt.UTC().Format("2006.01.02"))
+	tag("Variant", game.Variant)
+	seats := make(map[string]string)
+	results := make([]string, 0, len(game.Players))
+	for i, p := range game.Players {
+		seats[p.PlayerID] = seatName(i)
+		tag(fmt.Sprintf("Player%d", i+1), p.Name)
+		results = append(results, strconv.Itoa(game.FinalScores[p.PlayerID].FinalScore))
+	}
+	if seat, ok := seats[game.HostID]; ok {
+		tag("Host", seat)
+	}
+	if game.SeedHash != "" {
+		tag("SeedHash", game.SeedHash)
+	}
+	if game.Seed != "" {
+		tag("Seed", game.Seed)
+	}
+	tag("Result", strings.Join(results, "-"))
+	b.WriteString("\n")
+
+	var line []string
+	flush := func() {
+		if len(line) > 0 {
+			b.WriteString(strings.Join(line, " ") + "\n")
+		}
+		line = nil
+	}
+	for _, evt := range game.Timeline {
+		switch e := evt.Payload.(type) {
+		case *GameStartedEvent:
+			flush()
+			line = []string{strconv.Itoa(e.Turn) + ".", seats[e.CurrentPlayer]}
+		case *TurnChangedEvent:
+			flush()
+			line = []string{strconv.Itoa(e.Turn) + ".", seats[e.CurrentPlayer]}
+		case *TurnTimeoutEvent:
+			line = append(line, "timeout")
+		case *RollResultEvent:
+			line = append(line, formatRoll(e.Dice, e.HeldIndices))
+		case *ScoreUpdateEvent:
+			line = append(line, formatScore(e))
+		case *PlayerLeftEvent:
+			line = append(line, seats[e.PlayerID]+"-left")
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// formatRoll writes dice with the held ones in brackets
+func formatRoll(dice, held []int) string {
+	isHeld := make(map[int]bool, len(held))
+	for _, i := range held {
+		isHeld[i] = true
+	}
+
+	var b strings.Builder
+	for i, value := range dice {
+		if isHeld[i] {
+			fmt.Fprintf(&b, "[%d]", value)
+		} else {
+			b.WriteString(strconv.Itoa(value))
+		}
+	}
+	return b.String()
+}
+
+// formatScore writes a filled category as category:score[+bonus], or
+// category:x for a scratch
+func formatScore(e *ScoreUpdateEvent) string {
+	if e.Scratched {
+		return e.Category + ":x"
+	}
+	score := e.Category + ":" + strconv.Itoa(e.Score)
+	if e.Bonus > 0 {
+		score += "+" + strconv.Itoa(e.Bonus)
+	}
+	return score
+}
+
+// parseRecord reads a game record. Blank lines and lines starting with ";"
+// are ignored.
+func parseRecord(r io.Reader) (gameRecord, error) {
+	rec := gameRecord{tags: make(map[string]string)}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			m := recordTagPattern.FindStringSubmatch(line)
+			if m == nil {
+				return rec, fmt.Errorf("line %d: %w: bad tag", lineNumber, errRecordSyntax)
+			}
+			value, err := strconv.Unquote(m[2])
+			if err != nil {
+				return rec, fmt.Errorf("line %d: %w: bad tag value", lineNumber, errRecordSyntax)
+			}
+			rec.tags[m[1]] = value
+			continue
+		}
+
+		fields := strings.Fields(line)
+		m := recordTurnPattern.FindStringSubmatch(fields[0])
+		if m == nil || len(fields) < 2 || !recordSeatPattern.MatchString(fields[1]) {
+			return rec, fmt.Errorf("line %d: %w: expected a turn like \"1. P1 ...\"", lineNumber, errRecordSyntax)
+		}
+		number, _ := strconv.Atoi(m[1])
+		rec.turns = append(rec.turns, recordTurn{
+			line:   lineNumber,
+			number: number,
+			seat:   fields[1],
+			moves:  fields[2:],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return rec, err
+	}
+
+	for i := 0; ; i++ {
+		name, ok := rec.tags[fmt.Sprintf("Player%d", i+1)]
+		if !ok {
+			break
+		}
+		rec.players = append(rec.players, name)
+	}
+	if len(rec.players) == 0 || len(rec.players) > playerLimit {
+		return rec, fmt.Errorf("%w: needs between 1 and %d Player tags", errRecordSyntax, playerLimit)
+	}
+	return rec, nil
+}
+
+// parseRoll reads a roll written by formatRoll
+func parseRoll(move string) (dice, held []int, err error) {
+	for i := 0; i < len(move); i++ {
+		isHeld := move[i] == '['
+		if isHeld {
+			if i+2 >= len(move) || move[i+2] != ']' {
+				return nil, nil, fmt.Errorf("%w: bad roll %q", errRecordSyntax, move)
+			}
+			i++
+		}
+		if move[i] < '1' || move[i] > '6' {
+			return nil, nil, fmt.Errorf("%w: bad roll %q", errRecordSyntax, move)
+		}
+		if isHeld {
+			held = append(held, len(dice))
+		}
+		dice = append(dice, int(move[i]-'0'))
+		if isHeld {
+			i++
+		}
+	}
+	return dice, held, nil
+}
+
+// isRoll reports whether a move is a roll rather than a score or marker
+func isRoll(move string) bool {
+	return move != "" && (move[0] == '[' || (move[0] >= '1' && move[0] <= '6'))
+}
+
+// importRecord replays a game record through the game engine and returns
+// the game it produced. The record is rejected unless the engine, applying
+// the rules to the recorded dice, produces exactly the recorded game.
+// Provably fair games replay with their recorded dice; cmd/verifyrolls is
+// what checks those against the seed.
+func (gm *GameManager) importRecord(r io.Reader) (ArchivedGame, error) {
+	rec, err := parseRecord(r)
+	if err != nil {
+		return ArchivedGame{}, err
+	}
+
+	settings := gm.defaultSettings()
+	settings.Variant = rec.tags["Variant"]
+	settings.MinPlayers = 1
+	settings.TurnTimerSeconds = 0 // Timeouts are replayed where the record has them
+	settings.Private = true
+	if err := settings.Validate(); err != nil {
+		return ArchivedGame{}, fmt.Errorf("%w: %v", errRecordSyntax, err)
+	}
+	hostID := seatName(0)
+	if host, ok := rec.tags["Host"]; ok {
+		hostID = host
+	}
+
+	// Replay in a manager and room of their own, so nothing here can touch
+	// live rooms. The room is never published; see newRoom.
+	dice := &recordDice{}
+	engine := NewGameManager(gm.config, newMemoryStore())
+	engine.dice = dice
+	room := newRoom(rec.tags["Room"], dice, engine.clock, nil)
+	defer room.close()
+
+	room.addEvent("ROOM_CREATED", &RoomCreatedEvent{
+		HostID:   hostID,
+		Settings: settings,
+	})
+	for i, name := range rec.players {
+		room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{
+			PlayerID: seatName(i),
+			Name:     name,
+		})
+	}
+	if err := room.processCommand(hostID, &StartGameCommand{}); err != nil {
+		return ArchivedGame{}, fmt.Errorf("%w: %v", errRecordMismatch, err)
+	}
+
+	for _, turn := range rec.turns {
+		if err := engine.replayTurn(room, dice, turn); err != nil {
+			return ArchivedGame{}, fmt.Errorf("line %d: %w", turn.line, err)
+		}
+	}
+	if room.Phase != PhaseGameOver {
+		return ArchivedGame{}, errRecordIncomplete
+	}
+
+	game, _ := room.archivedGame()
+	if err := compareRecords(rec, exportRecord(game)); err != nil {
+		return ArchivedGame{}, err
+	}
+	return game, nil
+}
+
+// replayTurn plays one turn line of a record
+func (gm *GameManager) replayTurn(room *Room, dice *recordDice, turn recordTurn) error {
+	if !room.GameStarted || room.Phase == PhaseGameOver || len(room.PlayerOrder) == 0 {
+		return fmt.Errorf("%w: turn after the game ended", errRecordMismatch)
+	}
+	if turn.number != room.TurnNumber || turn.seat != room.PlayerOrder[room.CurrentPlayerIdx] {
+		return fmt.Errorf("%w: expected turn %d for %s", errRecordMismatch, room.TurnNumber, room.PlayerOrder[room.CurrentPlayerIdx])
+	}
+
+	moves := turn.moves
+	for i := 0; i < len(moves); i++ {
+		move := moves[i]
+		var err error
+		switch {
+		case move == "timeout":
+			// The engine rolls if nobody had, using the next roll's dice,
+			// and picks the category itself. The comparison at the end
+			// catches a record that disagrees with its choice.
+			if room.Phase == PhaseAwaitingRoll && i+1 < len(moves) && isRoll(moves[i+1]) {
+				i++
+				if err = loadRoll(dice, moves[i]); err != nil {
+					return err
+				}
+			}
+			room.handleTurnTimeout(room.TurnNumber)
+			i++ // The category the engine filled
+		case strings.HasSuffix(move, "-left"):
+			playerID := strings.TrimSuffix(move, "-left")
+			player, exists := room.Players[playerID]
+			if !exists {
+				return fmt.Errorf("%w: unknown player %q", errRecordSyntax, playerID)
+			}
+			gm.handlePlayerDisconnect(room, player)
+		case isRoll(move):
+			if err = loadRoll(dice, move); err != nil {
+				return err
+			}
+			_, held, _ := parseRoll(move)
+			err = room.processCommand(turn.seat, &RequestRollCommand{HeldIndices: held})
+		default:
+			category, score, ok := strings.Cut(move, ":")
+			if !ok {
+				return fmt.Errorf("%w: bad move %q", errRecordSyntax, move)
+			}
+			if score == "x" {
+				err = room.processCommand(turn.seat, &EndTurnCommand{Category: category})
+			} else {
+				err = room.processCommand(turn.seat, &CategoryChosenCommand{Category: category})
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", errRecordMismatch, move, err)
+		}
+	}
+	return nil
+}
+
+// loadRoll queues the dice a roll rolled, skipping the held ones
+func loadRoll(dice *recordDice, move string) error {
+	values, held, err := parseRoll(move)
+	if err != nil {
+		return err
+	}
+	isHeld := make(map[int]bool, len(held))
+	for _, i := range held {
+		isHeld[i] = true
+	}
+
+	dice.values = dice.values[:0]
+	for i, value := range values {
+		if !isHeld[i] {
+			dice.values = append(dice.values, value)
+		}
+	}
+	return nil
+}
+
+// compareRecords checks a record against the one the engine produced when
+// replaying it, reporting the first move that differs
+func compareRecords(rec gameRecord, replayed string) error {
+	engine, err := parseRecord(strings.NewReader(replayed))
+	if err != nil {
+		return err
+	}
+
+	for i, turn := range rec.turns {
+		if i >= len(engine.turns) {
+			return fmt.Errorf("line %d: %w: the game was already over", turn.line, errRecordMismatch)
+		}
+		want := engine.turns[i].moves
+		for j, move := range turn.moves {
+			if j >= len(want) || move != want[j] {
+				expected := "nothing"
+				if j < len(want) {
+					expected = strconv.Quote(want[j])
+				}
+				return fmt.Errorf("line %d: %w: recorded %q, the rules give %s", turn.line, errRecordMismatch, move, expected)
+			}
+		}
+		if len(turn.moves) < len(want) {
+			return fmt.Errorf("line %d: %w: turn is missing %q", turn.line, errRecordMismatch, want[len(turn.moves)])
+		}
+	}
+	if result, ok := rec.tags["Result"]; ok && result != engine.tags["Result"] {
+		return fmt.Errorf("%w: Result is %q, the rules give %q", errRecordMismatch, result, engine.tags["Result"])
+	}
+	return nil
+}
+
+// ExportRecord handles GET /games/{gameID}/record, returning an archived
+// game as a game record
+func (gm *GameManager) ExportRecord(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "gameID")
+
+	game, err := gm.store.LoadGame(gameID)
+	if errors.Is(err, errGameNotFound) {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("game_id", gameID).
+			Msg("Failed to load archived game")
+		http.Error(w, "Failed to load game", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, exportRecord(game))
+}
+
+// ImportRecord handles POST /games/import. It replays the game record in
+// the request body and returns the game the engine produced, or 422 if the
+// record doesn't follow the rules. Imported games aren't archived.
+func (gm *GameManager) ImportRecord(w http.ResponseWriter, r *http.Request) {
+	game, err := gm.importRecord(http.MaxBytesReader(w, r.Body, maxRecordSize))
+	if err != nil {
+		log.Debug().
+			Err(err).
+			Msg("Rejected game record")
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	json.NewEncoder(w).Encode(game)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// playTestGame plays a whole two-player game of 2 3 4 5 6 rolls, filling
+// the scorecard in order, with a held die in the first turn and P2
+// scratching yahtzee, and returns its archive
+func playTestGame(t *testing.T) ArchivedGame {
+	t.Helper()
+	clock := NewManualClock(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	room := startTestGame(t, RoomSettings{}, NewScriptedDice([]int{2, 3, 4, 5, 6}), clock)
+
+	for turn := 0; room.Phase != PhaseGameOver; turn++ {
+		playerID := room.PlayerOrder[room.CurrentPlayerIdx]
+		category := classicCategories[turn/2]
+
+		commands := []Command{&RequestRollCommand{}}
+		if turn == 0 {
+			// Rerolling five dice in all keeps the script in step
+			commands = append(commands,
+				&RequestRollCommand{HeldIndices: []int{0}},
+				&RequestRollCommand{HeldIndices: []int{1, 2, 3, 4}})
+		}
+		if category == "yahtzee" && playerID == "P2" {
+			commands = append(commands, &EndTurnCommand{Category: category})
+		} else {
+			commands = append(commands, &CategoryChosenCommand{Category: category})
+		}
+		for _, cmd := range commands {
+			if err := room.processCommand(playerID, cmd); err != nil {
+				t.Fatalf("turn %d: %s: %v", turn+1, cmd.commandType(), err)
+			}
+		}
+	}
+
+	game, ok := room.archivedGame()
+	if !ok {
+		t.Fatal("finished game has no archive")
+	}
+	return game
+}
+
+func TestGameRecordRoundTrip(t *testing.T) {
+	game := playTestGame(t)
+	record := exportRecord(game)
+	for _, want := range []string{"[Date \"2026.10.16\"]", "1. P1 23456 [2]2345 6[2][3][4][5] ones:0", "small_straight:30", "yahtzee:x"} {
+		if !strings.Contains(record, want) {
+			t.Errorf("record is missing %q:\n%s", want, record)
+		}
+	}
+
+	gm := NewGameManager(Config{}, newMemoryStore())
+	imported, err := gm.importRecord(strings.NewReader(record))
+	if err != nil {
+		t.Fatalf("importing the exported record: %v\n%s", err, record)
+	}
+	for _, p := range game.Players {
+		if got, want := imported.FinalScores[p.PlayerID].FinalScore, game.FinalScores[p.PlayerID].FinalScore; got != want {
+			t.Errorf("%s: imported final score %d, played %d", p.PlayerID, got, want)
+		}
+	}
+
+	// Tags the import can't know aside, the replay exports the same record
+	strip := func(record string) string {
+		var lines []string
+		for _, line := range strings.Split(record, "\n") {
+			if !strings.HasPrefix(line, "[Game ") && !strings.HasPrefix(line, "[Date ") {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	if got := exportRecord(imported); strip(got) != strip(record) {
+		t.Errorf("re-exported record differs:\n%s\nwant:\n%s", got, record)
+	}
+}
+
+func TestGameRecordRejectsTampering(t *testing.T) {
+	record := exportRecord(playTestGame(t))
+	lines := strings.Split(strings.TrimSpace(record), "\n")
+
+	tests := []struct {
+		name   string
+		record string
+		err    error
+	}{
+		{"changed score", strings.Replace(record, "large_straight:40", "large_straight:35", 1), errRecordMismatch},
+		{"changed dice", strings.Replace(record, "P2 23456 large_straight", "P2 23455 large_straight", 1), errRecordMismatch},
+		{"scratch of a taken category", strings.Replace(record, "yahtzee:x", "ones:x", 1), errRecordMismatch},
+		{"changed result", strings.Replace(record, "[Result \"", "[Result \"1", 1), errRecordMismatch},
+		{"missing last turn", strings.Join(lines[:len(lines)-1], "\n"), errRecordIncomplete},
+		{"garbage", "this is not a game record", errRecordSyntax},
+		{"no players", "[Variant \"yahtzee\"]\n\n1. P1 23456 ones:0\n", errRecordSyntax},
+	}
+
+	gm := NewGameManager(Config{}, newMemoryStore())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.record == record {
+				t.Fatal("tampering left the record unchanged")
+			}
+			if _, err := gm.importRecord(strings.NewReader(tt.record)); !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -4,12 +4,16 @@ go 1.21
 
 require github.com/go-chi/chi/v5 v5.1.0
 
-require github.com/go-chi/cors v1.2.1
+require (
+	github.com/go-chi/cors v1.2.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/rs/zerolog v1.34.0
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.14.0
+)
 
 require (
-	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
-	github.com/rs/zerolog v1.34.0 // indirect
-	golang.org/x/sys v0.12.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 )
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var errGuestUpgraded = errors.New("guest identity was upgraded to an account")
+
+// issueGuestCredential signs a guest's credential. Guests aren't stored
+// anywhere: the credential carries their ID and display name, and their
+// games are found by that ID like an account's.
+func (gm *GameManager) issueGuestCredential(guestID, name string) (string, time.Time) {
+	ttl := gm.config.GuestTTL
+	if ttl <= 0 {
+		ttl = defaultGuestTTL
+	}
+	expires := gm.clock.Now().Add(ttl)
+	return gm.tokens.encode(sessionClaims{
+		PlayerID: guestID,
+		Role:     RoleGuest,
+		Name:     name,
+		Expires:  expires.Unix(),
+	}), expires
+}
+
+// checkGuest refuses a guest credential once the guest has upgraded, so
+// their history is only reachable through the account from then on
+func (gm *GameManager) checkGuest(claims sessionClaims) error {
+	_, err := gm.store.LoadAccount(claims.PlayerID)
+	if err == nil {
+		return errGuestUpgraded
+	}
+	if errors.Is(err, errAccountNotFound) {
+		return nil
+	}
+	return err
+}
+
+// requestGuest returns the claims of a request's guest credential
+func (gm *GameManager) requestGuest(r *http.Request) (sessionClaims, error) {
+	claims, err := gm.signedIn(r, RoleGuest)
+	if err != nil {
+		return claims, err
+	}
+	return claims, gm.checkGuest(claims)
+}
+
+// CreateGuest handles POST /guests, issuing a guest credential for a
+// display name. Sending an existing credential keeps its identity, so
+// guests can rename themselves and renew the credential.
+func (gm *GameManager) CreateGuest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DisplayName string `json:"display_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.DisplayName) > maxDisplayNameSize {
+		http.Error(w, errInvalidDisplay.Error(), http.StatusBadRequest)
+		return
+	}
+
+	guestID := generatePlayerID()
+	if bearerToken(r) != "" {
+		claims, err := gm.requestGuest(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		guestID = claims.PlayerID
+		if req.DisplayName == "" {
+			req.DisplayName = claims.Name
+		}
+	}
+	if req.DisplayName == "" {
+		req.DisplayName = "Guest"
+	}
+
+	credential, expires := gm.issueGuestCredential(guestID, req.DisplayName)
+
+	log.Info().
+		Str("guest_id", guestID).
+		Str("display_name", req.DisplayName).
+		Msg("Issued guest credential")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"guest_id":     guestID,
+		"display_name": req.DisplayName,
+		"credential":   credential,
+		"expires_at":   expires,
+	})
+}
+
+// GetGuest handles GET /guests/me, returning a guest's finished games and
+// stats
+func (gm *GameManager) GetGuest(w http.ResponseWriter, r *http.Request) {
+	claims, err := gm.requestGuest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	games, stats, err := gm.playerHistory(claims.PlayerID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("guest_id", claims.PlayerID).
+			Msg("Failed to load guest history")
+		http.Error(w, "Failed to load guest", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"guest_id":     claims.PlayerID,
+		"display_name": claims.Name,
+		"games":        games,
+		"stats":        stats,
+	})
+}
+
+// UpgradeGuest handles POST /guests/upgrade. It registers an account, as
+// POST /accounts does, that takes over the guest's ID, so every game they
+// played as a guest is the account's. The guest credential stops working.
+func (gm *GameManager) UpgradeGuest(w http.ResponseWriter, r *http.Request) {
+	claims, err := gm.requestGuest(r)
+	if errors.Is(err, errGuestUpgraded) {
+		http.Error(w, "Guest was already upgraded", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gm.createAccount(w, r, claims.PlayerID, claims.Name)
+}
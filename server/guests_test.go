@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestGuestUpgradeKeepsHistory(t *testing.T) {
+	gm := NewGameManager(Config{}, newMemoryStore())
+	gm.dice = NewScriptedDice([]int{2, 3, 4, 5, 6})
+	srv := newTestServer(t, gm)
+
+	guest := srv.post("/guests", map[string]interface{}{"display_name": "Gus"})
+	credential, _ := guest["credential"].(string)
+	host := srv.post("/rooms", map[string]interface{}{"player_name": "Alice"})
+	code, _ := host["room_code"].(string)
+	_, seat := srv.request(http.MethodPost, "/rooms/join", credential, map[string]interface{}{"room_code": code})
+	var name string
+	room := srv.room(code)
+	room.do(func() {
+		if p, exists := room.Players[fmt.Sprint(seat["player_id"])]; exists {
+			name = p.Name
+		}
+	})
+	if name != "Gus" {
+		t.Fatalf("guest joined as %q, want Gus", name)
+	}
+	gameID := finishAccountGame(t, srv, code, host, seat)
+
+	status, me := srv.request(http.MethodGet, "/guests/me", credential, nil)
+	if status != http.StatusOK || fmt.Sprint(me["games"]) != fmt.Sprint([]interface{}{gameID}) {
+		t.Fatalf("GET /guests/me = %d %v, want game %s", status, me, gameID)
+	}
+
+	status, account := srv.request(http.MethodPost, "/guests/upgrade", credential, map[string]interface{}{
+		"username": "gus",
+		"password": "correct horse",
+	})
+	if status != http.StatusCreated || account["account_id"] != guest["guest_id"] || account["display_name"] != "Gus" {
+		t.Fatalf("upgrade = %d %v, want 201 for Gus under the guest's ID", status, account)
+	}
+
+	// The games the guest played are the account's now
+	status, me = srv.request(http.MethodGet, "/accounts/me", account["token"].(string), nil)
+	stats, _ := me["stats"].(map[string]interface{})
+	if status != http.StatusOK || fmt.Sprint(me["games"]) != fmt.Sprint([]interface{}{gameID}) || stats["games_played"] != float64(1) {
+		t.Errorf("GET /accounts/me = %d, games %v, stats %v; want the guest's game", status, me["games"], stats)
+	}
+
+	// The guest credential is no good once upgraded
+	requests := []struct {
+		method string
+		path   string
+		body   map[string]interface{}
+		status int
+	}{
+		{http.MethodGet, "/guests/me", nil, http.StatusUnauthorized},
+		{http.MethodPost, "/guests", map[string]interface{}{}, http.StatusUnauthorized},
+		{http.MethodPost, "/guests/upgrade", map[string]interface{}{"username": "gus2", "password": "correct horse"}, http.StatusConflict},
+		{http.MethodPost, "/rooms", map[string]interface{}{}, http.StatusUnauthorized},
+	}
+	for _, tt := range requests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if status, _ := srv.request(tt.method, tt.path, credential, tt.body); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestRestoredRoomRejoinsSignedInPlayers(t *testing.T) {
+	store := newMemoryStore()
+	config := Config{TokenKey: []byte("restart test key")}
+	before := newTestServer(t, NewGameManager(config, store))
+
+	_, account := before.request(http.MethodPost, "/accounts", "", map[string]interface{}{
+		"username": "Alice",
+		"password": "correct horse",
+	})
+	guest := before.post("/guests", map[string]interface{}{"display_name": "Gus"})
+	bearers := []string{account["token"].(string), guest["credential"].(string)}
+
+	_, host := before.request(http.MethodPost, "/rooms", bearers[0], map[string]interface{}{})
+	code, _ := host["room_code"].(string)
+	_, seat := before.request(http.MethodPost, "/rooms/join", bearers[1], map[string]interface{}{"room_code": code})
+	if errCode := before.command(code, host, map[string]interface{}{"type": "GAME_START"}); errCode != "" {
+		t.Fatalf("GAME_START: %s", errCode)
+	}
+	before.room(code).close()
+
+	after := newTestServer(t, NewGameManager(config, store))
+	if err := after.gm.LoadRooms(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signing in is enough to find their seat again
+	for i, p := range []map[string]interface{}{host, seat} {
+		status, rejoined := after.request(http.MethodPost, "/rooms/join", bearers[i], map[string]interface{}{"room_code": code})
+		if status != http.StatusOK || rejoined["is_viewer"] != false || rejoined["player_id"] != p["player_id"] {
+			t.Errorf("rejoin = %d %v, want %v back in their seat", status, rejoined, p["player_id"])
+		}
+	}
+}
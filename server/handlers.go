@@ -4,9 +4,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"math/big"
-	mrand "math/rand"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,26 +20,30 @@ import (
 
 // Player represents a player in a room
 type Player struct {
-	ID         string          `json:"player_id"`
-	Name       string          `json:"name"`
-	Token      string          `json:"-"`
-	Ready      bool            `json:"ready"`
-	Scores     map[string]int  `json:"scores"`
-	TotalScore int             `json:"total_score"`
-	IsViewer   bool            `json:"is_viewer"` // True if player rejoined after game started
-	LastSeen   time.Time       `json:"-"`
-	Conn       *websocket.Conn `json:"-"`
-	ConnMutex  sync.Mutex      `json:"-"`
+	ID           string         `json:"player_id"`
+	Name         string         `json:"name"`
+	Ready        bool           `json:"ready"`
+	Scores       map[string]int `json:"scores"`
+	TotalScore   int            `json:"total_score"`
+	YahtzeeBonus int            `json:"yahtzee_bonus"`
+	SavedRolls   int            `json:"saved_rolls"`          // Unused rolls banked by variants that save them
+	IsViewer     bool           `json:"is_viewer"`            // True if player joined after game started or left it
+	AccountID    string         `json:"account_id,omitempty"` // Set when they joined signed in or as a guest
+	LastSeen     time.Time      `json:"-"`
+	Conn         *clientConn    `json:"-"`
+	actions      []actionResult // Recent actions sent with an ID, oldest first
+	leaveTimer   Timer          // Frees their seat if they don't reconnect in time
 }
 
 // GameEvent represents a game event
 type GameEvent struct {
-	ID      int                    `json:"id"`
-	Type    string                 `json:"type"`
-	Payload map[string]interface{} `json:"event"`
+	ID      int         `json:"id"`
+	Type    string      `json:"type"`
+	Payload ServerEvent `json:"event"`
 }
 
-// Room represents a game room
+// Room represents a game room. Its state is owned by a single goroutine;
+// see room.go.
 type Room struct {
 	Code             string             `json:"room_code"`
 	Players          map[string]*Player `json:"players"`
@@ -46,11 +52,29 @@ type Room struct {
 	CurrentDice      []int              `json:"-"`
 	RollsLeft        int                `json:"-"`
 	GameStarted      bool               `json:"-"`
+	Phase            TurnPhase          `json:"-"`
+	TurnNumber       int                `json:"-"` // Increments every time a turn starts
+	RollNumber       int                `json:"-"` // Rolls taken so far this turn
+	TurnTimeout      time.Duration      `json:"-"`
+	TurnDeadline     time.Time          `json:"-"`
 	HostID           string             `json:"-"` // Original host (room creator)
+	Settings         RoomSettings       `json:"settings"`
+	Rules            Ruleset            `json:"-"`
 	Events           []GameEvent        `json:"-"`
-	EventMutex       sync.RWMutex       `json:"-"`
-	PlayerMutex      sync.RWMutex       `json:"-"`
 	LastActivity     time.Time          `json:"-"`
+	FairSeed         string             `json:"-"` // Secret seed, revealed at game end
+	FairRollCount    int                `json:"-"`
+	dice             DiceSource
+	clock            Clock
+	turnTimer        Timer
+	tickets          map[string]connectTicket // Unused WebSocket connect tickets
+	store            RoomStore
+	savedEvents      int           // Events already in the store
+	snapshotEvents   int           // Events covered by the store's latest snapshot
+	newEvents        chan struct{} // Closed and replaced whenever an event is recorded
+	commands         chan func()   // Work queued for the room's event loop
+	done             chan struct{}
+	closeOnce        sync.Once
 }
 
 // GameManager manages all game rooms
@@ -58,19 +82,27 @@ type GameManager struct {
 	rooms    map[string]*Room
 	mutex    sync.RWMutex
 	upgrader websocket.Upgrader
+	config   Config
+	dice     DiceSource
+	clock    Clock
+	tokens   *tokenSigner
+	store    RoomStore
 }
 
-// Categories for Yahtzee
-var Categories = []string{
-	"ones", "twos", "threes", "fours", "fives", "sixes",
-	"three_of_a_kind", "four_of_a_kind", "full_house",
-	"small_straight", "large_straight", "yahtzee",
-}
+var (
+	errGameAlreadyStarted = errors.New("game already started")
+	errNotHost            = errors.New("only the host can do that")
+)
 
-// NewGameManager creates a new game manager
-func NewGameManager() *GameManager {
+// NewGameManager creates a new game manager that saves rooms to store
+func NewGameManager(config Config, store RoomStore) *GameManager {
 	return &GameManager{
-		rooms: make(map[string]*Room),
+		rooms:  make(map[string]*Room),
+		config: config,
+		store:  store,
+		dice:   newDiceSource(config),
+		clock:  realClock{},
+		tokens: newTokenSigner(config.TokenKey, config.TokenTTL),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins for game clients
@@ -92,7 +124,7 @@ func generateRoomCode() string {
 	return string(code)
 }
 
-// generateToken creates a random auth token
+// generateToken creates a random secret, used for connect tickets
 func generateToken() string {
 	bytes := make([]byte, 32)
 	rand.Read(bytes)
@@ -108,42 +140,89 @@ func generatePlayerID() string {
 
 // CleanupExpiredRooms removes rooms with no activity
 func (gm *GameManager) CleanupExpiredRooms(timeout time.Duration) {
-	ticker := time.NewTicker(5 * time.Minute)
-	for range ticker.C {
-		gm.mutex.Lock()
-		now := time.Now()
-		for code, room := range gm.rooms {
-			if now.Sub(room.LastActivity) > timeout {
+	ticker := gm.clock.NewTicker(5 * time.Minute)
+	for range ticker.C() {
+		for _, room := range gm.snapshotRooms() {
+			expired := false
+			room.do(func() {
+				if gm.clock.Now().Sub(room.LastActivity) <= timeout {
+					return
+				}
+				expired = true
+				room.close() // Stop saving before the room is deleted
+
 				// Close all player connections
-				room.PlayerMutex.RLock()
 				for _, player := range room.Players {
 					if player.Conn != nil {
-						player.Conn.Close()
+						player.Conn.close()
 					}
 				}
-				room.PlayerMutex.RUnlock()
-				delete(gm.rooms, code)
+				room.stopTurnTimer()
+			})
+			if expired {
+				gm.removeRoom(room)
 				log.Info().
-					Str("room_code", code).
+					Str("room_code", room.Code).
 					Msg("Cleaned up expired room")
 			}
 		}
-		gm.mutex.Unlock()
+	}
+}
+
+// snapshotRooms returns the current rooms so callers can visit each one
+// without holding the manager lock while they wait on a room's event loop
+func (gm *GameManager) snapshotRooms() []*Room {
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	rooms := make([]*Room, 0, len(gm.rooms))
+	for _, room := range gm.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// removeRoom forgets a room and stops its event loop
+func (gm *GameManager) removeRoom(room *Room) {
+	gm.mutex.Lock()
+	if gm.rooms[room.Code] == room {
+		delete(gm.rooms, room.Code)
+	}
+	gm.mutex.Unlock()
+	room.close()
+
+	if err := gm.store.DeleteRoom(room.Code); err != nil {
+		log.Error().
+			Err(err).
+			Str("room_code", room.Code).
+			Msg("Failed to delete saved room")
 	}
 }
 
 // CreateRoom handles POST /rooms
 func (gm *GameManager) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		PlayerName string `json:"player_name"`
+		PlayerName string       `json:"player_name"`
+		Settings   RoomSettings `json:"settings"` // Optional: omitted fields use defaults
 	}
+	req.Settings = gm.defaultSettings()
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if req.PlayerName == "" {
-		req.PlayerName = "Player"
+	// Signing in or using a guest identity is optional, but a bad token is
+	// refused rather than quietly ignored
+	who, err := gm.requestIdentity(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req.PlayerName = playerName(req.PlayerName, who)
+
+	if err := req.Settings.Validate(); err != nil {
+		http.Error(w, "Invalid settings: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	gm.mutex.Lock()
@@ -159,27 +238,21 @@ func (gm *GameManager) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	playerID := generatePlayerID()
-	token := generateToken()
+	token := gm.issueToken(roomCode, playerID, false)
 
-	player := &Player{
-		ID:       playerID,
-		Name:     req.PlayerName,
-		Token:    token,
-		Ready:    false,
-		Scores:   make(map[string]int),
-		LastSeen: time.Now(),
-	}
-
-	room := &Room{
-		Code:         roomCode,
-		Players:      map[string]*Player{playerID: player},
-		PlayerOrder:  []string{playerID},
-		HostID:       playerID, // Set the original host
-		CurrentDice:  []int{1, 1, 1, 1, 1},
-		RollsLeft:    3,
-		Events:       []GameEvent{},
-		LastActivity: time.Now(),
-	}
+	// Set up before the room is published; see newRoom
+	room := newRoom(roomCode, gm.dice, gm.clock, gm.store)
+	room.addEvent("ROOM_CREATED", &RoomCreatedEvent{
+		HostID:   playerID, // The original host
+		Settings: req.Settings,
+	})
+	room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{
+		PlayerID:  playerID,
+		Name:      req.PlayerName,
+		AccountID: who.id(),
+	})
+	room.Players[playerID].LastSeen = gm.clock.Now()
+	room.persist()
 
 	gm.rooms[roomCode] = room
 
@@ -187,13 +260,16 @@ func (gm *GameManager) CreateRoom(w http.ResponseWriter, r *http.Request) {
 		Str("room_code", roomCode).
 		Str("player_id", playerID).
 		Str("player_name", req.PlayerName).
+		Interface("settings", room.Settings).
 		Msg("Created room")
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"room_code":     roomCode,
-		"player_id":     playerID,
-		"token":         token,
-		"last_event_id": 0,
+		"room_code":        roomCode,
+		"player_id":        playerID,
+		"token":            token,
+		"settings":         room.Settings,
+		"last_event_id":    len(room.Events),
+		"protocol_version": ProtocolVersion,
 	})
 }
 
@@ -210,9 +286,12 @@ func (gm *GameManager) JoinRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.PlayerName == "" {
-		req.PlayerName = "Player"
+	who, err := gm.requestIdentity(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
+	req.PlayerName = playerName(req.PlayerName, who)
 
 	gm.mutex.RLock()
 	room, exists := gm.rooms[req.RoomCode]
@@ -227,141 +306,181 @@ func (gm *GameManager) JoinRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	room.PlayerMutex.Lock()
-	defer room.PlayerMutex.Unlock()
-
-	// Check if this is a rejoin attempt with valid credentials
-	if req.PlayerID != "" && req.Token != "" {
-		if existingPlayer, exists := room.Players[req.PlayerID]; exists {
-			if existingPlayer.Token == req.Token {
-				// Valid rejoin - update name and last seen
-				existingPlayer.Name = req.PlayerName
-				existingPlayer.LastSeen = time.Now()
+	// The room may close between the lookup and running on its event loop
+	ok := room.do(func() {
+		// Check if this is a rejoin: valid credentials for a player, or an
+		// account or guest that already has a seat here. Invalid credentials fall
+		// through to joining as someone new.
+		var existingPlayer *Player
+		if req.PlayerID != "" && req.Token != "" {
+			if claims, err := gm.verifyToken(room.Code, req.PlayerID, req.Token); err == nil {
+				existingPlayer = room.authenticate(claims)
+			}
+		}
+		if existingPlayer == nil && who != nil {
+			existingPlayer = room.identityPlayer(who.ID)
+		}
+		if existingPlayer != nil {
+			playerID := existingPlayer.ID
 
-				// Check if player is still in PlayerOrder (they can still play)
-				isInOrder := false
-				for _, pid := range room.PlayerOrder {
-					if pid == req.PlayerID {
-						isInOrder = true
-						break
-					}
+			// Check if player is still in PlayerOrder (they can still play)
+			isInOrder := false
+			for _, pid := range room.PlayerOrder {
+				if pid == playerID {
+					isInOrder = true
+					break
 				}
+			}
 
-				// If game has started OR player is not in order (they left), they're a viewer
-				// Once you leave, you can't come back as an active player
-				isViewer := room.GameStarted || !isInOrder
-				existingPlayer.IsViewer = isViewer
-
-				room.LastActivity = time.Now()
+			// A player still in the turn order takes their seat back, such as
+			// after a restart. Once you leave, you can only come back as a
+			// viewer.
+			isViewer := !isInOrder
+
+			// Valid rejoin - update name and last seen
+			room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{
+				PlayerID:  playerID,
+				Name:      req.PlayerName,
+				IsViewer:  isViewer,
+				AccountID: who.id(),
+			})
+			existingPlayer.LastSeen = gm.clock.Now()
+			room.LastActivity = gm.clock.Now()
+
+			log.Info().
+				Str("room_code", req.RoomCode).
+				Str("player_id", playerID).
+				Str("player_name", req.PlayerName).
+				Bool("is_viewer", isViewer).
+				Bool("is_in_order", isInOrder).
+				Msg("Player rejoined room")
+
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"room_code":        req.RoomCode,
+				"player_id":        playerID,
+				"token":            gm.issueToken(room.Code, playerID, isViewer),
+				"is_viewer":        isViewer,
+				"settings":         room.Settings,
+				"last_event_id":    len(room.Events),
+				"protocol_version": ProtocolVersion,
+			})
+			return
+		}
 
-				log.Info().
+		// If game has started, allow joining as viewer only
+		if room.GameStarted {
+			if !room.Settings.AllowSpectators {
+				log.Debug().
 					Str("room_code", req.RoomCode).
-					Str("player_id", req.PlayerID).
 					Str("player_name", req.PlayerName).
-					Bool("is_viewer", isViewer).
-					Bool("is_in_order", isInOrder).
-					Msg("Player rejoined room")
-
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"room_code":     req.RoomCode,
-					"player_id":     req.PlayerID,
-					"token":         req.Token,
-					"is_viewer":     isViewer,
-					"last_event_id": len(room.Events),
-				})
+					Msg("Spectator join attempt to room that disallows spectators")
+				http.Error(w, "Spectators are not allowed in this room", http.StatusForbidden)
 				return
 			}
+
+			// Create a new viewer player
+			playerID := generatePlayerID()
+			token := gm.issueToken(room.Code, playerID, true)
+
+			// Viewers don't get a place in PlayerOrder - they can't play
+			room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{
+				PlayerID:  playerID,
+				Name:      req.PlayerName,
+				IsViewer:  true, // Always a viewer if joining after game started
+				AccountID: who.id(),
+			})
+			room.Players[playerID].LastSeen = gm.clock.Now()
+			room.LastActivity = gm.clock.Now()
+
+			log.Info().
+				Str("room_code", req.RoomCode).
+				Str("player_id", playerID).
+				Str("player_name", req.PlayerName).
+				Msg("New viewer joined room")
+
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"room_code":        req.RoomCode,
+				"player_id":        playerID,
+				"token":            token,
+				"is_viewer":        true,
+				"settings":         room.Settings,
+				"last_event_id":    len(room.Events),
+				"protocol_version": ProtocolVersion,
+			})
+			return
 		}
-		// Invalid rejoin credentials - fall through to create new viewer if game started
-	}
 
-	// If game has started, allow joining as viewer only
-	if room.GameStarted {
-		// Create a new viewer player
-		playerID := generatePlayerID()
-		token := generateToken()
-
-		player := &Player{
-			ID:       playerID,
-			Name:     req.PlayerName,
-			Token:    token,
-			Ready:    false,
-			Scores:   make(map[string]int),
-			IsViewer: true, // Always a viewer if joining after game started
-			LastSeen: time.Now(),
+		// New player join - only allowed if game hasn't started
+
+		if len(room.Players) >= room.Settings.MaxPlayers {
+			log.Debug().
+				Str("room_code", req.RoomCode).
+				Str("player_name", req.PlayerName).
+				Int("player_count", len(room.Players)).
+				Msg("Join attempt to full room")
+			http.Error(w, "Room is full", http.StatusForbidden)
+			return
 		}
 
-		room.Players[playerID] = player
-		// Don't add to PlayerOrder - viewers can't play
-		room.LastActivity = time.Now()
+		playerID := generatePlayerID()
+		token := gm.issueToken(room.Code, playerID, false)
+
+		room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{
+			PlayerID:  playerID,
+			Name:      req.PlayerName,
+			AccountID: who.id(),
+		})
+		room.Players[playerID].LastSeen = gm.clock.Now()
+		room.LastActivity = gm.clock.Now()
 
 		log.Info().
 			Str("room_code", req.RoomCode).
 			Str("player_id", playerID).
 			Str("player_name", req.PlayerName).
-			Msg("New viewer joined room")
+			Int("player_count", len(room.Players)).
+			Msg("Player joined room")
 
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"room_code":     req.RoomCode,
-			"player_id":     playerID,
-			"token":         token,
-			"is_viewer":     true,
-			"last_event_id": len(room.Events),
+			"room_code":        req.RoomCode,
+			"player_id":        playerID,
+			"token":            token,
+			"is_viewer":        false,
+			"settings":         room.Settings,
+			"last_event_id":    len(room.Events),
+			"protocol_version": ProtocolVersion,
 		})
-		return
+	})
+	if !ok {
+		http.Error(w, "Room not found", http.StatusNotFound)
 	}
+}
 
-	// New player join - only allowed if game hasn't started
+// WebSocket handles GET /rooms/{roomCode}/ws?ticket=T&protocol=V. The
+// ticket comes from CreateTicket.
+func (gm *GameManager) WebSocket(w http.ResponseWriter, r *http.Request) {
+	roomCode := chi.URLParam(r, "roomCode")
+	ticket := r.URL.Query().Get("ticket")
 
-	if len(room.Players) >= 6 {
+	if err := checkProtocol(r.URL.Query().Get("protocol")); err != nil {
 		log.Debug().
-			Str("room_code", req.RoomCode).
-			Str("player_name", req.PlayerName).
-			Int("player_count", len(room.Players)).
-			Msg("Join attempt to full room")
-		http.Error(w, "Room is full", http.StatusForbidden)
+			Err(err).
+			Str("room_code", roomCode).
+			Msg("WebSocket connection with unsupported protocol")
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	playerID := generatePlayerID()
-	token := generateToken()
-
-	player := &Player{
-		ID:       playerID,
-		Name:     req.PlayerName,
-		Token:    token,
-		Ready:    false,
-		Scores:   make(map[string]int),
-		IsViewer: false,
-		LastSeen: time.Now(),
+	// Optional: the last event ID the client saw, to resume from
+	since := -1
+	if value := r.URL.Query().Get("since"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = n
 	}
 
-	room.Players[playerID] = player
-	room.PlayerOrder = append(room.PlayerOrder, playerID)
-	room.LastActivity = time.Now()
-
-	log.Info().
-		Str("room_code", req.RoomCode).
-		Str("player_id", playerID).
-		Str("player_name", req.PlayerName).
-		Int("player_count", len(room.Players)).
-		Msg("Player joined room")
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"room_code":     req.RoomCode,
-		"player_id":     playerID,
-		"token":         token,
-		"is_viewer":     false,
-		"last_event_id": len(room.Events),
-	})
-}
-
-// WebSocket handles GET /rooms/{roomCode}/ws
-func (gm *GameManager) WebSocket(w http.ResponseWriter, r *http.Request) {
-	roomCode := chi.URLParam(r, "roomCode")
-	playerID := r.URL.Query().Get("player_id")
-	token := r.URL.Query().Get("token")
-
 	gm.mutex.RLock()
 	room, exists := gm.rooms[roomCode]
 	gm.mutex.RUnlock()
@@ -369,29 +488,28 @@ func (gm *GameManager) WebSocket(w http.ResponseWriter, r *http.Request) {
 	if !exists {
 		log.Debug().
 			Str("room_code", roomCode).
-			Str("player_id", playerID).
 			Msg("WebSocket connection to non-existent room")
 		http.Error(w, "Room not found", http.StatusNotFound)
 		return
 	}
 
-	// Verify player token
-	room.PlayerMutex.RLock()
-	player, playerExists := room.Players[playerID]
-	room.PlayerMutex.RUnlock()
+	// Redeem the connect ticket
+	var player *Player
+	room.do(func() {
+		player = room.redeemTicket(ticket)
+	})
 
-	if !playerExists || player.Token != token {
+	if player == nil {
 		log.Warn().
 			Str("room_code", roomCode).
-			Str("player_id", playerID).
-			Bool("player_exists", playerExists).
 			Msg("WebSocket connection unauthorized")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	playerID := player.ID
 
 	// Upgrade to WebSocket
-	conn, err := gm.upgrader.Upgrade(w, r, nil)
+	ws, err := gm.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -400,16 +518,42 @@ func (gm *GameManager) WebSocket(w http.ResponseWriter, r *http.Request) {
 			Msg("WebSocket upgrade failed")
 		return
 	}
+	conn := newClientConn(ws, gm.config.HeartbeatTimeout)
+
+	connected := false
+	room.do(func() {
+		connected = gm.connectPlayer(room, player, conn, since)
+	})
+	if !connected {
+		conn.close()
+		return
+	}
+
+	// Handle incoming messages
+	gm.handlePlayerMessages(room, player, conn)
+}
 
-	// Store connection
-	player.ConnMutex.Lock()
+// connectPlayer attaches a new connection to a player and sends them the
+// room's current state. A client resuming with since already has the state
+// as of that event, so it's sent only the events after it instead. It runs
+// on the room's event loop and reports false if the player is no longer in
+// the room.
+func (gm *GameManager) connectPlayer(room *Room, player *Player, conn *clientConn, since int) bool {
+	// The player may have left while the connection was being upgraded
+	if room.Players[player.ID] != player {
+		return false
+	}
+	roomCode := room.Code
+	playerID := player.ID
+
+	// Store connection; a player back within the grace period keeps their seat
 	oldConn := player.Conn
 	player.Conn = conn
-	player.ConnMutex.Unlock()
+	player.stopLeaveTimer()
 
 	// Close old connection if exists
 	if oldConn != nil {
-		oldConn.Close()
+		oldConn.close()
 		log.Debug().
 			Str("room_code", roomCode).
 			Str("player_id", playerID).
@@ -421,29 +565,30 @@ func (gm *GameManager) WebSocket(w http.ResponseWriter, r *http.Request) {
 		Str("player_id", playerID).
 		Msg("WebSocket connected")
 
+	// A since past the end of the history can't be resumed from
+	resuming := since >= 0 && since <= len(room.Events)
+
 	// Send viewer status to reconnecting player
 	if player.IsViewer {
-		gm.sendToPlayer(player, map[string]interface{}{
-			"type":      "VIEWER_MODE",
-			"player_id": playerID,
-			"message":   "You are viewing this game. You cannot interact.",
+		gm.sendToPlayer(player, "VIEWER_MODE", &ViewerModeEvent{
+			PlayerID: playerID,
+			Message:  "You are viewing this game. You cannot interact.",
 		})
 
 		// If game has started, send complete current game state
-		if room.GameStarted {
-			room.PlayerMutex.RLock()
-			playersData := make(map[string]interface{})
-			playersList := make([]map[string]interface{}, 0, len(room.PlayerOrder))
+		if room.GameStarted && !resuming {
+			playersData := make(map[string]PlayerSummary)
+			playersList := make([]PlayerSummary, 0, len(room.PlayerOrder))
 
 			// Build player list in turn order (active players only)
 			for _, pid := range room.PlayerOrder {
 				if p, exists := room.Players[pid]; exists {
-					pData := map[string]interface{}{
-						"player_id":   pid,
-						"name":        p.Name,
-						"ready":       p.Ready,
-						"total_score": p.TotalScore,
-						"scores":      p.Scores,
+					pData := PlayerSummary{
+						PlayerID:   pid,
+						Name:       p.Name,
+						Ready:      p.Ready,
+						TotalScore: p.TotalScore,
+						Scores:     p.Scores,
 					}
 					playersData[pid] = pData
 					playersList = append(playersList, pData)
@@ -453,13 +598,13 @@ func (gm *GameManager) WebSocket(w http.ResponseWriter, r *http.Request) {
 			// Also include all players (including viewers) in the full data
 			for id, p := range room.Players {
 				if _, exists := playersData[id]; !exists {
-					playersData[id] = map[string]interface{}{
-						"player_id":   id,
-						"name":        p.Name,
-						"ready":       p.Ready,
-						"total_score": p.TotalScore,
-						"scores":      p.Scores,
-						"is_viewer":   p.IsViewer,
+					playersData[id] = PlayerSummary{
+						PlayerID:   id,
+						Name:       p.Name,
+						Ready:      p.Ready,
+						TotalScore: p.TotalScore,
+						Scores:     p.Scores,
+						IsViewer:   p.IsViewer,
 					}
 				}
 			}
@@ -468,83 +613,100 @@ func (gm *GameManager) WebSocket(w http.ResponseWriter, r *http.Request) {
 			if len(room.PlayerOrder) > 0 && room.CurrentPlayerIdx < len(room.PlayerOrder) {
 				currentPlayerID = room.PlayerOrder[room.CurrentPlayerIdx]
 			}
-			room.PlayerMutex.RUnlock()
+
+			state := &GameStateEvent{
+				TurnPosition:  room.turnPosition(),
+				Players:       playersData,
+				PlayerList:    playersList,
+				TurnOrder:     room.PlayerOrder,
+				CurrentPlayer: currentPlayerID,
+				Dice:          room.CurrentDice,
+				RollsLeft:     room.RollsLeft,
+				Phase:         room.Phase.String(),
+				LastEventID:   len(room.Events),
+			}
 
 			// Get event history for viewer
-			room.EventMutex.RLock()
-			eventHistory := make([]map[string]interface{}, 0, len(room.Events))
+			state.EventHistory = make([]ServerEvent, 0, len(room.Events))
 			for _, evt := range room.Events {
-				// Create a copy of the payload to avoid mutating the original
-				eventCopy := make(map[string]interface{})
-				for k, v := range evt.Payload {
-					eventCopy[k] = v
-				}
-				eventHistory = append(eventHistory, eventCopy)
+				state.EventHistory = append(state.EventHistory, clientEvent(evt.Payload))
 			}
-			room.EventMutex.RUnlock()
-
-			gm.sendToPlayer(player, map[string]interface{}{
-				"type":           "GAME_STATE",
-				"players":        playersData,
-				"player_list":    playersList,
-				"turn_order":     room.PlayerOrder,
-				"current_player": currentPlayerID,
-				"dice":           room.CurrentDice,
-				"rolls_left":     room.RollsLeft,
-				"event_history":  eventHistory,
-			})
+			gm.sendToPlayer(player, "GAME_STATE", state)
 		}
 	}
 
-	// Send existing players to new connection (including self)
-	room.PlayerMutex.RLock()
-	for id, p := range room.Players {
-		gm.sendToPlayer(player, map[string]interface{}{
-			"type":      "PLAYER_JOINED",
-			"player_id": id,
-			"name":      p.Name,
-			"is_host":   id == room.HostID,
-			"is_viewer": p.IsViewer,
+	if !resuming {
+		// Send existing players to new connection (including self)
+		for id, p := range room.Players {
+			gm.sendToPlayer(player, "PLAYER_JOINED", &PlayerJoinedEvent{
+				PlayerID: id,
+				Name:     p.Name,
+				IsHost:   id == room.HostID,
+				IsViewer: p.IsViewer,
+			})
+		}
+
+		// Send current room settings so the lobby can display them
+		gm.sendToPlayer(player, "ROOM_SETTINGS", &SettingsEvent{
+			Settings:   room.Settings,
+			Categories: room.Rules.Categories(),
 		})
 	}
-	room.PlayerMutex.RUnlock()
 
 	// Broadcast join to all other players (only if not a silent reconnection)
 	// For viewers rejoining, we don't need to broadcast
 	if !player.IsViewer || !room.GameStarted {
-		room.broadcast(map[string]interface{}{
-			"type":      "PLAYER_JOINED",
-			"player_id": playerID,
-			"name":      player.Name,
-			"is_host":   playerID == room.HostID,
-			"is_viewer": player.IsViewer,
+		room.broadcast("PLAYER_JOINED", &PlayerJoinedEvent{
+			PlayerID: playerID,
+			Name:     player.Name,
+			IsHost:   playerID == room.HostID,
+			IsViewer: player.IsViewer,
 		}, playerID)
 	}
 
-	// Handle incoming messages
-	gm.handlePlayerMessages(room, player)
+	// Replay the events a resuming client missed. Live events can't slip in
+	// between because this runs on the room's event loop.
+	if resuming && since < len(room.Events) {
+		for _, evt := range room.Events[since:] {
+			gm.sendToPlayer(player, evt.Type, evt.Payload)
+		}
+		log.Debug().
+			Str("room_code", roomCode).
+			Str("player_id", playerID).
+			Int("since", since).
+			Int("replayed", len(room.Events)-since).
+			Msg("Replayed missed events")
+	}
+	return true
 }
 
-// handlePlayerMessages reads messages from a player's WebSocket
-func (gm *GameManager) handlePlayerMessages(room *Room, player *Player) {
+// handlePlayerMessages reads messages from a player's WebSocket and hands
+// each one to the room's event loop
+func (gm *GameManager) handlePlayerMessages(room *Room, player *Player, conn *clientConn) {
 	defer func() {
-		player.ConnMutex.Lock()
-		if player.Conn != nil {
-			player.Conn.Close()
+		ok := room.do(func() {
+			// A reconnect replaces the connection; only the newest one counts
+			if player.Conn != conn {
+				conn.close()
+				return
+			}
+
+			player.Conn.close()
 			player.Conn = nil
-		}
-		player.ConnMutex.Unlock()
-		log.Info().
-			Str("player_id", player.ID).
-			Str("room_code", room.Code).
-			Msg("WebSocket disconnected")
+			log.Info().
+				Str("player_id", player.ID).
+				Str("room_code", room.Code).
+				Msg("WebSocket disconnected")
 
-		// Handle player disconnection
-		gm.handlePlayerDisconnect(room, player)
+			gm.holdSeat(room, player)
+		})
+		if !ok {
+			conn.close()
+		}
 	}()
 
 	for {
-		_, message, err := player.Conn.ReadMessage()
+		_, message, err := conn.ws.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Warn().
@@ -562,434 +724,544 @@ func (gm *GameManager) handlePlayerMessages(room *Room, player *Player) {
 			break
 		}
 
-		var event map[string]interface{}
-		if err := json.Unmarshal(message, &event); err != nil {
+		cmd, header, err := decodeCommand(message)
+		if err != nil {
 			log.Warn().
 				Err(err).
 				Str("player_id", player.ID).
 				Str("room_code", room.Code).
 				Str("message", string(message)).
 				Msg("Invalid message from player")
-			continue
 		}
 
-		room.LastActivity = time.Now()
-		player.LastSeen = time.Now()
+		ok := room.do(func() {
+			if err == nil {
+				err = gm.handlePlayerCommand(room, player, cmd)
+			}
 
-		// Unwrap event if it's in wrapped format: {"type": "event", "event": {...}}
-		eventType, _ := event["type"].(string)
-		if eventType == "event" {
-			if innerEvent, ok := event["event"].(map[string]interface{}); ok {
-				event = innerEvent
-				eventType, _ = event["type"].(string)
+			// Tell the client why the command failed, or acknowledge it
+			if eventType, reply := commandResult(header.Type, header.RequestID, err, len(room.Events)); reply != nil {
+				gm.sendToPlayer(player, eventType, reply)
 			}
+		})
+		if !ok {
+			break // Room was closed
 		}
+	}
+}
 
-		// Add player_id to event if not present
-		if _, ok := event["player_id"]; !ok {
-			event["player_id"] = player.ID
-		}
+// holdSeat keeps a disconnected player's seat for the reconnect grace
+// period, so they can reconnect and resume where they left off. Viewers,
+// and players who don't reconnect in time, leave the room. It runs on the
+// room's event loop.
+func (gm *GameManager) holdSeat(room *Room, player *Player) {
+	grace := gm.config.ReconnectGrace
+	if grace <= 0 || player.IsViewer || room.Phase == PhaseGameOver {
+		gm.handlePlayerDisconnect(room, player)
+		return
+	}
 
-		// Ignore events from viewers
-		if player.IsViewer {
-			log.Debug().
+	player.stopLeaveTimer()
+	var timer Timer
+	timer = room.clock.AfterFunc(grace, func() {
+		room.do(func() {
+			// They reconnected, or left some other way, in the meantime
+			if player.leaveTimer != timer || room.Players[player.ID] != player {
+				return
+			}
+			player.leaveTimer = nil
+
+			log.Info().
 				Str("player_id", player.ID).
 				Str("room_code", room.Code).
-				Str("event_type", eventType).
-				Msg("Ignoring event from viewer")
-			continue
-		}
+				Msg("Player didn't reconnect in time")
+			gm.handlePlayerDisconnect(room, player)
+		})
+	})
+	player.leaveTimer = timer
+}
 
-		// Process event as authority
-		log.Trace().
+// stopLeaveTimer cancels a pending holdSeat timeout
+func (player *Player) stopLeaveTimer() {
+	if player.leaveTimer != nil {
+		player.leaveTimer.Stop()
+		player.leaveTimer = nil
+	}
+}
+
+// handlePlayerCommand applies one command from a player's connection. It
+// runs on the room's event loop.
+func (gm *GameManager) handlePlayerCommand(room *Room, player *Player, cmd Command) error {
+	room.LastActivity = gm.clock.Now()
+	player.LastSeen = gm.clock.Now()
+
+	// Ignore commands from viewers
+	if player.IsViewer {
+		log.Debug().
 			Str("player_id", player.ID).
 			Str("room_code", room.Code).
-			Str("event_type", eventType).
-			Interface("event", event).
-			Msg("Processing game event")
-		room.processEvent(eventType, event)
+			Str("event_type", cmd.commandType()).
+			Msg("Ignoring event from viewer")
+		return errViewerReadOnly
 	}
-}
 
-// sendToPlayer sends a message to a specific player
-func (gm *GameManager) sendToPlayer(player *Player, event map[string]interface{}) {
-	player.ConnMutex.Lock()
-	defer player.ConnMutex.Unlock()
+	// Process command as authority
+	log.Trace().
+		Str("player_id", player.ID).
+		Str("room_code", room.Code).
+		Str("event_type", cmd.commandType()).
+		Interface("event", cmd).
+		Msg("Processing game event")
+	return room.processCommand(player.ID, cmd)
+}
 
+// sendToPlayer queues an event for a specific player
+func (gm *GameManager) sendToPlayer(player *Player, eventType string, event ServerEvent) {
 	if player.Conn == nil {
 		return
 	}
 
-	data, err := json.Marshal(event)
+	event.header().Type = eventType
+	data, err := json.Marshal(clientEvent(event))
 	if err != nil {
 		return
 	}
 
-	if err := player.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
+	if !player.Conn.enqueue(data) {
 		log.Warn().
-			Err(err).
 			Str("player_id", player.ID).
 			Msg("Failed to send message to player")
 	} else {
 		log.Trace().
 			Str("player_id", player.ID).
-			Str("event_type", event["type"].(string)).
+			Str("event_type", eventType).
 			Interface("event", event).
 			Msg("Message sent to player")
 	}
 }
 
-// broadcast sends an event to all players in a room
-func (room *Room) broadcast(event map[string]interface{}, excludePlayerID string) {
-	room.PlayerMutex.RLock()
-	defer room.PlayerMutex.RUnlock()
-
-	data, err := json.Marshal(event)
+// broadcast queues an event for all players in a room. The event is
+// marshalled once and the same bytes are shared by every connection.
+func (room *Room) broadcast(eventType string, event ServerEvent, excludePlayerID string) {
+	event.header().Type = eventType
+	data, err := json.Marshal(clientEvent(event))
 	if err != nil {
 		return
 	}
 
 	for id, player := range room.Players {
-		if id == excludePlayerID {
+		if id == excludePlayerID || player.Conn == nil {
 			continue
 		}
 
-		player.ConnMutex.Lock()
-		if player.Conn != nil {
-			if err := player.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
-				log.Warn().
-					Err(err).
-					Str("player_id", id).
-					Msg("Failed to broadcast message to player")
-			}
+		if !player.Conn.enqueue(data) {
+			log.Warn().
+				Str("player_id", id).
+				Str("room_code", room.Code).
+				Msg("Player can't keep up, dropping connection")
 		}
-		player.ConnMutex.Unlock()
 	}
 }
 
 // broadcastAll sends an event to all players including sender
-func (room *Room) broadcastAll(event map[string]interface{}) {
+func (room *Room) broadcastAll(eventType string, event ServerEvent) {
 	log.Trace().
 		Str("room_code", room.Code).
+		Str("event_type", eventType).
 		Interface("event", event).
 		Msg("Broadcasting event to all players")
-	room.broadcast(event, "")
+	room.broadcast(eventType, event, "")
 }
 
-// addEvent adds an event to history and broadcasts to all players
-func (room *Room) addEvent(eventType string, payload map[string]interface{}) {
-	room.EventMutex.Lock()
-	payload["type"] = eventType
-	event := GameEvent{
-		ID:      len(room.Events) + 1,
+// addEvent applies an event to the room, adds it to history and
+// broadcasts it to all players. The event carries its ID so clients can
+// resume after it.
+func (room *Room) addEvent(eventType string, event ServerEvent) {
+	id := len(room.Events) + 1
+	header := event.header()
+	header.Type = eventType
+	header.EventID = id
+	room.apply(event)
+	room.Events = append(room.Events, GameEvent{
+		ID:      id,
 		Type:    eventType,
-		Payload: payload,
-	}
-	room.Events = append(room.Events, event)
-	room.EventMutex.Unlock()
+		Payload: event,
+	})
+
+	// Wake long-polling clients
+	close(room.newEvents)
+	room.newEvents = make(chan struct{})
 
 	// Broadcast to all players
-	room.broadcastAll(payload)
+	room.broadcastAll(eventType, event)
 }
 
-// processEvent handles game logic for incoming events
-func (room *Room) processEvent(eventType string, event map[string]interface{}) {
-	switch eventType {
-	case "PLAYER_READY":
-		room.handlePlayerReady(event)
-	case "GAME_START", "START_GAME":
-		room.handleGameStart(event)
-	case "REQUEST_ROLL":
-		room.handleRequestRoll(event)
-	case "CATEGORY_CHOSEN":
-		room.handleCategoryChosen(event)
-	case "REQUEST_END_TURN":
-		room.handleEndTurn(event)
-	case "CHAT_MESSAGE":
-		room.addEvent("CHAT_MESSAGE", event)
+// processCommand handles game logic for a command sent by a player and
+// returns why it was rejected, if it was
+func (room *Room) processCommand(playerID string, cmd Command) error {
+	// Answer a repeated action the way it was answered the first time
+	player := room.Players[playerID]
+	actionID := cmd.actionID()
+	if player != nil && actionID != "" {
+		if previous := player.findAction(actionID); previous != nil {
+			log.Debug().
+				Str("player_id", playerID).
+				Str("room_code", room.Code).
+				Str("action_id", actionID).
+				Msg("Ignoring repeated action")
+			return previous.err
+		}
+	}
+
+	err := room.checkTarget(cmd)
+	if err == nil {
+		err = room.applyCommand(playerID, cmd)
+	}
+	if player != nil && actionID != "" {
+		player.recordAction(actionID, err)
 	}
-}
 
-func (room *Room) handlePlayerReady(event map[string]interface{}) {
-	playerID, _ := event["player_id"].(string)
-	ready, _ := event["ready"].(bool)
+	if err != nil {
+		log.Debug().
+			Err(err).
+			Str("player_id", playerID).
+			Str("room_code", room.Code).
+			Str("event_type", cmd.commandType()).
+			Str("phase", room.Phase.String()).
+			Msg("Rejected game event")
+	}
+	return err
+}
 
-	room.PlayerMutex.Lock()
-	if player, exists := room.Players[playerID]; exists {
-		player.Ready = ready
+// applyCommand dispatches a command to the handler for its type
+func (room *Room) applyCommand(playerID string, cmd Command) error {
+	var err error
+	switch c := cmd.(type) {
+	case *PlayerReadyCommand:
+		err = room.handlePlayerReady(playerID, c)
+	case *StartGameCommand:
+		err = room.handleGameStart(playerID)
+	case *RequestRollCommand:
+		err = room.handleRequestRoll(playerID, c)
+	case *CategoryChosenCommand:
+		err = room.handleCategoryChosen(playerID, c)
+	case *EndTurnCommand:
+		err = room.handleEndTurn(playerID, c)
+	case *SettingsUpdateCommand:
+		err = room.handleSettingsUpdate(playerID, c)
+	case *ChatMessageCommand:
+		err = room.handleChatMessage(playerID, c)
+	default:
+		err = errUnknownCommand
 	}
-	room.PlayerMutex.Unlock()
+	return err
+}
 
-	room.addEvent("PLAYER_READY", event)
+func (room *Room) handlePlayerReady(playerID string, cmd *PlayerReadyCommand) error {
+	room.addEvent("PLAYER_READY", &PlayerReadyEvent{
+		PlayerID: playerID,
+		Ready:    cmd.Ready,
+	})
+	return nil
 }
 
-func (room *Room) handleGameStart(event map[string]interface{}) {
+func (room *Room) handleGameStart(playerID string) error {
 	if room.GameStarted {
-		return
+		return errGameAlreadyStarted
 	}
 
 	// Validate that only the host can start the game
-	playerID, _ := event["player_id"].(string)
 	if room.HostID != playerID {
 		log.Warn().
 			Str("player_id", playerID).
 			Str("room_code", room.Code).
 			Str("host_id", room.HostID).
 			Msg("Non-host player attempted to start game")
-		return
+		return errNotHost
+	}
+
+	if len(room.PlayerOrder) < room.Settings.MinPlayers {
+		return errNotEnoughPlayers
 	}
 
-	room.GameStarted = true
-	room.RollsLeft = 3
-	room.CurrentDice = []int{1, 1, 1, 1, 1}
+	seedHash := ""
+	if room.Settings.ProvablyFair {
+		var err error
+		if seedHash, err = room.startFairDice(); err != nil {
+			return err
+		}
+	}
 
 	// Shuffle player order randomly
 	shuffledOrder := make([]string, len(room.PlayerOrder))
 	copy(shuffledOrder, room.PlayerOrder)
-	mrand.Shuffle(len(shuffledOrder), func(i, j int) {
+	room.dice.Shuffle(len(shuffledOrder), func(i, j int) {
 		shuffledOrder[i], shuffledOrder[j] = shuffledOrder[j], shuffledOrder[i]
 	})
-	room.PlayerOrder = shuffledOrder
-	room.CurrentPlayerIdx = 0
 
-	room.PlayerMutex.Lock()
-	playersData := make(map[string]interface{})
-	playersList := make([]map[string]interface{}, 0, len(room.Players))
+	// Scores start over, so every summary starts empty
+	playersData := make(map[string]PlayerSummary)
+	playersList := make([]PlayerSummary, 0, len(room.Players))
 	for id, p := range room.Players {
-		p.Scores = make(map[string]int)
-		p.TotalScore = 0
-		pData := map[string]interface{}{
-			"player_id":   id,
-			"name":        p.Name,
-			"ready":       p.Ready,
-			"total_score": 0,
+		pData := PlayerSummary{
+			PlayerID: id,
+			Name:     p.Name,
+			Ready:    p.Ready,
+			Scores:   map[string]int{},
 		}
 		playersData[id] = pData
 		playersList = append(playersList, pData)
 	}
-	room.PlayerMutex.Unlock()
 
 	firstPlayer := ""
-	if len(room.PlayerOrder) > 0 {
-		firstPlayer = room.PlayerOrder[0]
+	if len(shuffledOrder) > 0 {
+		firstPlayer = shuffledOrder[0]
 	}
 
-	room.addEvent("GAME_STARTED", map[string]interface{}{
-		"players":        playersData,
-		"player_list":    playersList,
-		"turn_order":     room.PlayerOrder,
-		"current_player": firstPlayer,
+	room.addEvent("GAME_STARTED", &GameStartedEvent{
+		TurnTiming:    room.nextTurnTiming(),
+		TurnPosition:  TurnPosition{Turn: room.TurnNumber + 1},
+		Players:       playersData,
+		PlayerList:    playersList,
+		TurnOrder:     shuffledOrder,
+		CurrentPlayer: firstPlayer,
+		RollsLeft:     room.Rules.RollsPerTurn(),
+		Phase:         PhaseAwaitingRoll.String(),
+		Variant:       room.Rules.Name(),
+		Categories:    room.Rules.Categories(),
+		ProvablyFair:  room.Settings.ProvablyFair,
+		SeedHash:      seedHash,
 	})
+	room.syncTurnTimer()
 
 	log.Info().
 		Str("room_code", room.Code).
 		Str("current_player", firstPlayer).
 		Int("player_count", len(room.Players)).
 		Msg("Game started")
+	return nil
 }
 
-func (room *Room) handleRequestRoll(event map[string]interface{}) {
-	playerID, _ := event["player_id"].(string)
-	heldIndices, _ := event["held_indices"].([]interface{})
-
-	// Validate turn
-	if len(room.PlayerOrder) == 0 || room.PlayerOrder[room.CurrentPlayerIdx] != playerID {
-		log.Debug().
-			Str("player_id", playerID).
-			Str("room_code", room.Code).
-			Str("expected_player", func() string {
-				if len(room.PlayerOrder) > 0 {
-					return room.PlayerOrder[room.CurrentPlayerIdx]
-				}
-				return "none"
-			}()).
-			Msg("Invalid turn: player attempted roll out of turn")
-		return
+func (room *Room) handleRequestRoll(playerID string, cmd *RequestRollCommand) error {
+	nonce := cmd.ClientNonce
+	if err := room.checkTurnAction(playerID, ActionRoll); err != nil {
+		return err
 	}
-
-	if room.RollsLeft <= 0 {
-		log.Debug().
-			Str("player_id", playerID).
-			Str("room_code", room.Code).
-			Msg("Invalid roll: no rolls left")
-		return
+	if len(nonce) > maxClientNonceLength {
+		return errNonceTooLong
 	}
 
-	// Convert held indices
+	// Convert held indices; nothing can be held before the first roll
 	held := make(map[int]bool)
-	for _, idx := range heldIndices {
-		if i, ok := idx.(float64); ok {
-			held[int(i)] = true
+	if room.Phase != PhaseAwaitingRoll {
+		for _, i := range cmd.HeldIndices {
+			held[i] = true
 		}
 	}
 
 	// Roll non-held dice
-	for i := 0; i < 5; i++ {
-		if !held[i] {
-			room.CurrentDice[i] = mrand.Intn(6) + 1
+	var fairValues []int
+	rollIndex := 0
+	if room.Settings.ProvablyFair {
+		fairValues, rollIndex = room.fairRoll(nonce)
+	}
+	dice := append([]int(nil), room.CurrentDice...)
+	heldList := make([]int, 0, len(held))
+	for i := range dice {
+		switch {
+		case held[i]:
+			heldList = append(heldList, i)
+		case fairValues != nil:
+			dice[i] = fairValues[i]
+		default:
+			dice[i] = room.dice.Roll(6)
 		}
 	}
-	room.RollsLeft--
+	rollsLeft := room.RollsLeft - 1
+
+	result := &RollResultEvent{
+		TurnPosition: TurnPosition{Turn: room.TurnNumber, Roll: room.RollNumber + 1},
+		PlayerID:     playerID,
+		Dice:         dice,
+		HeldIndices:  heldList,
+		RollsLeft:    rollsLeft,
+		Phase:        afterRoll(rollsLeft).String(),
+		Variant:      room.Rules.Name(),
+		Categories:   room.Rules.Categories(),
+	}
+	if room.Settings.ProvablyFair {
+		result.RollIndex = &rollIndex
+		result.ClientNonce = &nonce
+	}
+	room.addEvent("ROLL_RESULT", result)
 
 	log.Debug().
 		Str("player_id", playerID).
 		Str("room_code", room.Code).
 		Ints("dice", room.CurrentDice).
 		Int("rolls_left", room.RollsLeft).
+		Str("phase", room.Phase.String()).
 		Msg("Dice rolled")
-
-	room.addEvent("ROLL_RESULT", map[string]interface{}{
-		"player_id":  playerID,
-		"dice":       room.CurrentDice,
-		"rolls_left": room.RollsLeft,
-	})
+	return nil
 }
 
-func (room *Room) handleCategoryChosen(event map[string]interface{}) {
-	playerID, _ := event["player_id"].(string)
-	category, _ := event["category"].(string)
+func (room *Room) handleCategoryChosen(playerID string, cmd *CategoryChosenCommand) error {
+	if err := room.checkTurnAction(playerID, ActionScore); err != nil {
+		return err
+	}
 
-	// Handle score as either float64 or int
-	var score int
-	switch v := event["score"].(type) {
-	case float64:
-		score = int(v)
-	case int:
-		score = v
+	return room.fillCategory(playerID, cmd.Category, false)
+}
+
+// handleEndTurn ends a turn by scratching a category: the player scores
+// zero in it. A turn can't be skipped without filling a box.
+func (room *Room) handleEndTurn(playerID string, cmd *EndTurnCommand) error {
+	if err := room.checkTurnAction(playerID, ActionScratch); err != nil {
+		return err
+	}
+	if cmd.Category == "" {
+		return errCategoryRequired
 	}
 
-	// Validate turn
-	if len(room.PlayerOrder) == 0 || room.PlayerOrder[room.CurrentPlayerIdx] != playerID {
-		log.Debug().
-			Str("player_id", playerID).
-			Str("room_code", room.Code).
-			Str("expected_player", func() string {
-				if len(room.PlayerOrder) > 0 {
-					return room.PlayerOrder[room.CurrentPlayerIdx]
-				}
-				return "none"
-			}()).
-			Msg("Invalid turn: player attempted to choose category out of turn")
-		return
+	return room.fillCategory(playerID, cmd.Category, true)
+}
+
+// handleChatMessage relays a chat message to the room
+func (room *Room) handleChatMessage(playerID string, cmd *ChatMessageCommand) error {
+	playerName := ""
+	if player, exists := room.Players[playerID]; exists {
+		playerName = player.Name
 	}
 
-	room.PlayerMutex.Lock()
+	room.addEvent("CHAT_MESSAGE", &ChatMessageEvent{
+		PlayerID:   playerID,
+		PlayerName: playerName,
+		Message:    cmd.Message,
+	})
+	return nil
+}
+
+// fillCategory records the current player's score in a category, then ends
+// the game or passes the turn on. A scratch always scores zero.
+func (room *Room) fillCategory(playerID, category string, scratch bool) error {
 	player, exists := room.Players[playerID]
 	if !exists {
-		room.PlayerMutex.Unlock()
 		log.Warn().
 			Str("player_id", playerID).
 			Str("room_code", room.Code).
 			Msg("Category chosen by non-existent player")
-		return
+		return errNotYourTurn
 	}
 
-	// Check category not taken
-	if _, taken := player.Scores[category]; taken {
-		room.PlayerMutex.Unlock()
-		log.Debug().
-			Str("player_id", playerID).
-			Str("room_code", room.Code).
-			Str("category", category).
-			Msg("Category already taken")
-		return
+	// Compute the score server-side; any client-supplied score is ignored.
+	// Scratches follow the same placement rules, such as the forced Joker
+	// box, and then score nothing.
+	score, bonus, err := room.Rules.ScoreTurn(player.Scores, category, room.CurrentDice)
+	if err != nil {
+		return err
+	}
+	if scratch {
+		score, bonus = 0, 0
 	}
 
-	player.Scores[category] = score
-	player.TotalScore += score
-	room.PlayerMutex.Unlock()
+	room.addEvent("SCORE_UPDATE", &ScoreUpdateEvent{
+		PlayerID:     playerID,
+		Category:     category,
+		Score:        score,
+		Bonus:        bonus,
+		YahtzeeBonus: player.YahtzeeBonus + bonus,
+		Scratched:    scratch,
+	})
 
 	log.Info().
 		Str("player_id", playerID).
 		Str("room_code", room.Code).
 		Str("category", category).
 		Int("score", score).
+		Int("yahtzee_bonus", bonus).
+		Bool("scratched", scratch).
 		Int("total_score", player.TotalScore).
 		Msg("Score updated")
 
-	room.addEvent("SCORE_UPDATE", map[string]interface{}{
-		"player_id": playerID,
-		"category":  category,
-		"score":     score,
-	})
-
-	// Auto advance turn after scoring
-	room.advanceTurn()
-
-	// Check game end
-	room.checkGameEnd()
-}
-
-func (room *Room) handleEndTurn(event map[string]interface{}) {
-	playerID, _ := event["player_id"].(string)
-
-	// Validate turn
-	if len(room.PlayerOrder) == 0 || room.PlayerOrder[room.CurrentPlayerIdx] != playerID {
-		return
+	// Check game end, otherwise auto advance turn after scoring
+	if room.checkGameEnd() {
+		return nil
 	}
-
 	room.advanceTurn()
+	return nil
 }
 
 func (room *Room) advanceTurn() {
-	// Advance turn
-	room.CurrentPlayerIdx = (room.CurrentPlayerIdx + 1) % len(room.PlayerOrder)
-	room.RollsLeft = 3
-	room.CurrentDice = []int{0, 0, 0, 0, 0}
+	previousPlayer := room.PlayerOrder[room.CurrentPlayerIdx]
+	nextPlayer := room.PlayerOrder[(room.CurrentPlayerIdx+1)%len(room.PlayerOrder)]
+	room.changeTurn(previousPlayer, nextPlayer)
 
-	newPlayerID := room.PlayerOrder[room.CurrentPlayerIdx]
 	log.Debug().
 		Str("room_code", room.Code).
-		Str("current_player", newPlayerID).
+		Str("current_player", nextPlayer).
 		Int("player_index", room.CurrentPlayerIdx).
 		Msg("Turn advanced")
+}
+
+// changeTurn starts nextPlayer's turn with a fresh set of dice, plus any
+// rolls they saved from earlier turns. previousPlayer, when set, banks
+// their unused rolls in variants that save them.
+func (room *Room) changeTurn(previousPlayer, nextPlayer string) {
+	savedRolls := 0
+	if player, exists := room.Players[nextPlayer]; exists {
+		savedRolls = player.SavedRolls
+	}
+	if nextPlayer == previousPlayer && room.Rules.SavesUnusedRolls() {
+		savedRolls = room.RollsLeft
+	}
 
-	room.addEvent("TURN_CHANGED", map[string]interface{}{
-		"current_player": newPlayerID,
-		"rolls_left":     3,
+	room.addEvent("TURN_CHANGED", &TurnChangedEvent{
+		TurnTiming:     room.nextTurnTiming(),
+		TurnPosition:   TurnPosition{Turn: room.TurnNumber + 1},
+		PreviousPlayer: previousPlayer,
+		CurrentPlayer:  nextPlayer,
+		RollsLeft:      room.Rules.RollsPerTurn() + savedRolls,
+		Phase:          PhaseAwaitingRoll.String(),
 	})
+	room.syncTurnTimer()
 }
 
-func (room *Room) checkGameEnd() {
-	room.PlayerMutex.RLock()
-	defer room.PlayerMutex.RUnlock()
+// checkGameEnd ends the game once every scorecard is full and reports
+// whether it did
+func (room *Room) checkGameEnd() bool {
 
-	// Check if all players have filled all categories
-	for _, player := range room.Players {
-		if player.IsViewer {
-			continue // Skip viewers
-		}
-		if len(player.Scores) < len(Categories) {
-			return
+	// Check if all players still in the turn order have filled all categories
+	if len(room.PlayerOrder) == 0 {
+		return false
+	}
+	for _, pid := range room.PlayerOrder {
+		if player, exists := room.Players[pid]; exists && len(player.Scores) < len(room.Rules.Categories()) {
+			return false
 		}
 	}
 
-	// Calculate final scores with upper bonus
-	finalScores := make(map[string]interface{})
+	// Calculate final scores with upper and Yahtzee bonuses
+	finalScores := make(map[string]FinalScore)
 	highestScore := -1
 	var winners []string // Track multiple winners for draws
 
-	for id, player := range room.Players {
-		if player.IsViewer {
-			continue // Skip viewers in scoring
-		}
-
-		upperTotal := 0
-		for _, cat := range []string{"ones", "twos", "threes", "fours", "fives", "sixes"} {
-			upperTotal += player.Scores[cat]
+	// Only players still in the turn order are scored; viewers and anyone
+	// who left the game aren't
+	for _, id := range room.PlayerOrder {
+		player, exists := room.Players[id]
+		if !exists {
+			continue
 		}
 
-		bonus := 0
-		if upperTotal >= 63 {
-			bonus = 35
-		}
-		finalTotal := player.TotalScore + bonus
+		bonus := room.Rules.UpperBonus(player.Scores)
+		finalTotal := player.TotalScore + bonus + player.YahtzeeBonus
 
-		finalScores[id] = map[string]interface{}{
-			"name":        player.Name,
-			"base_score":  player.TotalScore,
-			"upper_bonus": bonus,
-			"final_score": finalTotal,
+		finalScores[id] = FinalScore{
+			Name:         player.Name,
+			BaseScore:    player.TotalScore,
+			UpperBonus:   bonus,
+			YahtzeeBonus: player.YahtzeeBonus,
+			FinalScore:   finalTotal,
 		}
 
 		if finalTotal > highestScore {
@@ -1021,12 +1293,23 @@ func (room *Room) checkGameEnd() {
 		}
 	}
 
-	room.addEvent("GAME_END", map[string]interface{}{
-		"final_scores": finalScores,
-		"winner_id":    winnerID,
-		"winner_name":  winnerName,
-		"is_draw":      isDraw,
-	})
+	end := &GameEndEvent{
+		Variant:     room.Rules.Name(),
+		Categories:  room.Rules.Categories(),
+		FinalScores: finalScores,
+		WinnerID:    winnerID,
+		WinnerName:  winnerName,
+		IsDraw:      isDraw,
+		GameID:      generateGameID(),
+		FinishedAt:  room.clock.Now().UnixMilli(),
+	}
+	if room.Settings.ProvablyFair {
+		// Reveal the seed so every roll can be re-derived
+		end.Seed = room.FairSeed
+	}
+	room.addEvent("GAME_END", end)
+	room.stopTurnTimer()
+	room.archiveGame()
 
 	log.Info().
 		Str("room_code", room.Code).
@@ -1035,11 +1318,11 @@ func (room *Room) checkGameEnd() {
 		Int("final_score", highestScore).
 		Bool("is_draw", isDraw).
 		Msg("Game ended")
+	return true
 }
 
 // handlePlayerDisconnect handles when a player disconnects
 func (gm *GameManager) handlePlayerDisconnect(room *Room, player *Player) {
-	room.PlayerMutex.Lock()
 
 	// Check if player was the host (use HostID, not PlayerOrder[0] which gets shuffled)
 	isHost := room.HostID == player.ID
@@ -1051,87 +1334,40 @@ func (gm *GameManager) handlePlayerDisconnect(room *Room, player *Player) {
 
 	// If game has started, keep player in room but mark as disconnected
 	// This allows them to rejoin as a viewer later
-	// Only remove completely if game hasn't started
-	if !gameStarted {
-		delete(room.Players, player.ID)
-	} else {
-		// Just close the connection, keep player data for rejoin
-		player.ConnMutex.Lock()
-		if player.Conn != nil {
-			player.Conn.Close()
-			player.Conn = nil
-		}
-		player.ConnMutex.Unlock()
-		// Don't remove from Players map - allows rejoin
-		// But we still need to handle turn order updates
+	if gameStarted && player.Conn != nil {
+		player.Conn.close()
+		player.Conn = nil
 	}
 
 	// Check if the current player is leaving (before removing from order)
 	wasCurrentPlayer := len(room.PlayerOrder) > 0 && room.CurrentPlayerIdx < len(room.PlayerOrder) && room.PlayerOrder[room.CurrentPlayerIdx] == player.ID
+	wasSeated := slices.Contains(room.PlayerOrder, player.ID)
+
+	// Remove player from PlayerOrder (they can rejoin but won't be in turn
+	// order), and from the room entirely if the game hasn't started. The
+	// leaving player is gone or disconnected, so they don't see this.
+	room.addEvent("PLAYER_LEFT", &PlayerLeftEvent{
+		PlayerID:   playerID,
+		PlayerName: playerName,
+		IsHost:     isHost,
+	})
 
-	// Find the index of the leaving player in the order (before removing)
-	leavingPlayerIdx := -1
-	for i, pid := range room.PlayerOrder {
-		if pid == player.ID {
-			leavingPlayerIdx = i
-			break
-		}
-	}
-
-	// Remove player from PlayerOrder (they can rejoin but won't be in turn order)
-	newOrder := make([]string, 0, len(room.PlayerOrder))
-	for _, pid := range room.PlayerOrder {
-		if pid != player.ID {
-			newOrder = append(newOrder, pid)
-		}
-	}
-	room.PlayerOrder = newOrder
-
-	// Update CurrentPlayerIdx if needed
-	if wasCurrentPlayer && len(room.PlayerOrder) > 0 {
-		// If current player left, advance to next player (same index, but array is shorter)
-		room.CurrentPlayerIdx = room.CurrentPlayerIdx % len(room.PlayerOrder)
-	} else if leavingPlayerIdx >= 0 && leavingPlayerIdx < room.CurrentPlayerIdx {
-		// If a player BEFORE the current player left, decrement the index
-		room.CurrentPlayerIdx--
-	} else if room.CurrentPlayerIdx >= len(room.PlayerOrder) && len(room.PlayerOrder) > 0 {
-		// Index out of bounds after removal, wrap around
-		room.CurrentPlayerIdx = room.CurrentPlayerIdx % len(room.PlayerOrder)
-	} else if len(room.PlayerOrder) == 0 {
-		room.CurrentPlayerIdx = 0
-	}
-
-	// Count active players (not disconnected)
-	remainingPlayers := 0
-	for _, p := range room.Players {
-		if p.Conn != nil {
-			remainingPlayers++
-		}
-	}
+	// Count the players still in the game. Seats are what matter, not open
+	// WebSockets: players on long-polling never have a connection.
+	remainingPlayers := len(room.PlayerOrder)
 	// If game hasn't started, use actual player count
 	if !gameStarted {
 		remainingPlayers = len(room.Players)
 	}
-	room.PlayerMutex.Unlock()
 
-	// Broadcast PLAYER_LEFT event to remaining players (after unlocking)
-	room.broadcast(map[string]interface{}{
-		"type":        "PLAYER_LEFT",
-		"player_id":   playerID,
-		"player_name": playerName,
-		"is_host":     isHost,
-	}, playerID)
+	// With them out of the turn order, everyone left may have a full card.
+	// A finished game has no turns left to hand on.
+	gameOver := gameStarted && (room.Phase == PhaseGameOver || room.checkGameEnd())
 
 	// Only send TURN_CHANGED if the current player left (not just any player)
-	if gameStarted && wasCurrentPlayer && len(room.PlayerOrder) > 0 {
+	if gameStarted && !gameOver && wasCurrentPlayer && len(room.PlayerOrder) > 0 {
 		currentPlayerID := room.PlayerOrder[room.CurrentPlayerIdx]
-		room.RollsLeft = 3
-		room.CurrentDice = []int{0, 0, 0, 0, 0}
-
-		room.addEvent("TURN_CHANGED", map[string]interface{}{
-			"current_player": currentPlayerID,
-			"rolls_left":     3,
-		})
+		room.changeTurn("", currentPlayerID)
 
 		log.Debug().
 			Str("room_code", room.Code).
@@ -1147,71 +1383,52 @@ func (gm *GameManager) handlePlayerDisconnect(room *Room, player *Player) {
 			Str("player_id", playerID).
 			Msg("Host disconnected during game, ending room")
 
-		// Broadcast ROOM_ENDED event
-		room.broadcastAll(map[string]interface{}{
-			"type":   "ROOM_ENDED",
-			"reason": "host_disconnected",
+		room.addEvent("ROOM_ENDED", &RoomEndedEvent{
+			Reason: "host_disconnected",
 		})
 
 		// Close all remaining player connections
-		room.PlayerMutex.RLock()
 		playersToClose := make([]*Player, 0, len(room.Players))
 		for _, p := range room.Players {
 			playersToClose = append(playersToClose, p)
 		}
-		room.PlayerMutex.RUnlock()
 
 		for _, p := range playersToClose {
-			p.ConnMutex.Lock()
 			if p.Conn != nil {
-				p.Conn.Close()
+				p.Conn.close()
 				p.Conn = nil
 			}
-			p.ConnMutex.Unlock()
 		}
 
 		// Remove room from manager
-		gm.mutex.Lock()
-		delete(gm.rooms, room.Code)
-		gm.mutex.Unlock()
+		room.stopTurnTimer()
+		gm.removeRoom(room)
 		return
 	}
 
-	// Check if only 1 player remains and game was started
-	if remainingPlayers == 1 && gameStarted {
+	// Check if only 1 player remains and game was started. Viewers coming
+	// and going don't change who's playing.
+	if remainingPlayers == 1 && gameStarted && wasSeated {
 		log.Info().
 			Str("room_code", room.Code).
 			Int("remaining_players", remainingPlayers).
 			Msg("Only 1 player remaining, ending room")
 
-		// Broadcast ROOM_ENDED event
-		room.broadcastAll(map[string]interface{}{
-			"type":   "ROOM_ENDED",
-			"reason": "insufficient_players",
+		room.addEvent("ROOM_ENDED", &RoomEndedEvent{
+			Reason: "insufficient_players",
 		})
 
-		// Close remaining player connection
-		room.PlayerMutex.RLock()
-		var remainingPlayer *Player
+		// Close the remaining player's connection, and any viewers'
 		for _, p := range room.Players {
-			remainingPlayer = p
-			break
-		}
-		room.PlayerMutex.RUnlock()
-
-		if remainingPlayer != nil {
-			remainingPlayer.ConnMutex.Lock()
-			if remainingPlayer.Conn != nil {
-				remainingPlayer.Conn.Close()
-				remainingPlayer.Conn = nil
+			if p.Conn != nil {
+				p.Conn.close()
+				p.Conn = nil
 			}
-			remainingPlayer.ConnMutex.Unlock()
 		}
 
 		// Remove room from manager
-		gm.mutex.Lock()
-		delete(gm.rooms, room.Code)
-		gm.mutex.Unlock()
+		room.stopTurnTimer()
+		gm.removeRoom(room)
 		return
 	}
 
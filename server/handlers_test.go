@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// startTestGame starts a game in a room that's never published, so tests
+// can drive it directly. The players sit in the order given, P1 and P2
+// when none are, and P1 hosts.
+func startTestGame(t *testing.T, settings RoomSettings, dice DiceSource, clock Clock, players ...string) *Room {
+	t.Helper()
+	if len(players) == 0 {
+		players = []string{"P1", "P2"}
+	}
+	room := newRoom("TEST", dice, clock, nil)
+	t.Cleanup(room.close)
+
+	room.addEvent("ROOM_CREATED", &RoomCreatedEvent{HostID: players[0], Settings: settings})
+	for _, id := range players {
+		room.addEvent("PLAYER_ADDED", &PlayerAddedEvent{PlayerID: id, Name: "Player " + id})
+	}
+	if err := room.processCommand(players[0], &StartGameCommand{}); err != nil {
+		t.Fatalf("start game: %v", err)
+	}
+	return room
+}
+
+// lastEvent returns the data of the room's latest event of a type, or nil
+func lastEvent(room *Room, eventType string) ServerEvent {
+	for i := len(room.Events) - 1; i >= 0; i-- {
+		if room.Events[i].Type == eventType {
+			return room.Events[i].Payload
+		}
+	}
+	return nil
+}
+
+func TestScratchFollowsJokerRules(t *testing.T) {
+	room := startTestGame(t, RoomSettings{}, NewScriptedDice([]int{4}), realClock{})
+	room.Players["P1"].Scores["yahtzee"] = 50
+
+	if err := room.processCommand("P1", &RequestRollCommand{}); err != nil {
+		t.Fatalf("roll: %v", err)
+	}
+	err := room.processCommand("P1", &EndTurnCommand{Category: "full_house"})
+	if !errors.Is(err, errJokerUpperRequired) {
+		t.Fatalf("scratching full_house: err = %v, want %v", err, errJokerUpperRequired)
+	}
+
+	if err := room.processCommand("P1", &EndTurnCommand{Category: "fours"}); err != nil {
+		t.Fatalf("scratching fours: %v", err)
+	}
+	update, _ := lastEvent(room, "SCORE_UPDATE").(*ScoreUpdateEvent)
+	if update == nil || !update.Scratched || update.Score != 0 || update.Bonus != 0 {
+		t.Errorf("SCORE_UPDATE = %+v, want a scratch of fours for 0", update)
+	}
+	if room.PlayerOrder[room.CurrentPlayerIdx] != "P2" {
+		t.Errorf("current player = %q, want P2", room.PlayerOrder[room.CurrentPlayerIdx])
+	}
+}
+
+func TestDepartedPlayerIsNotScored(t *testing.T) {
+	room := startTestGame(t, RoomSettings{}, NewScriptedDice([]int{6}), realClock{}, "P1", "P2", "P3")
+
+	// P1 and P2 have scratched everything; P3 leaves on their last turn
+	// with the best card
+	for _, id := range []string{"P1", "P2"} {
+		for _, category := range classicCategories {
+			room.Players[id].Scores[category] = 0
+		}
+	}
+	leaver := room.Players["P3"]
+	for _, category := range classicCategories[:len(classicCategories)-1] {
+		leaver.Scores[category] = 8
+	}
+	leaver.TotalScore = 88
+	room.CurrentPlayerIdx = 2
+
+	gm := NewGameManager(Config{}, newMemoryStore())
+	gm.handlePlayerDisconnect(room, leaver)
+
+	end, _ := lastEvent(room, "GAME_END").(*GameEndEvent)
+	if end == nil {
+		t.Fatal("game didn't end when the last player with turns left went")
+	}
+	if _, scored := end.FinalScores["P3"]; scored {
+		t.Errorf("final scores %v include the player who left", end.FinalScores)
+	}
+	if end.WinnerID != "P1" || !end.IsDraw || len(end.FinalScores) != 2 {
+		t.Errorf("GAME_END = winner %s, draw %v, scores %v; want a draw between P1 and P2", end.WinnerID, end.IsDraw, end.FinalScores)
+	}
+
+	game, _ := room.archivedGame()
+	if game.WinnerID == "P3" {
+		t.Error("archive names the player who left as winner")
+	}
+}
+
+// startResumeGame starts a game between a host and a guest connected over
+// WebSockets to a server that holds seats for grace. The host plays first.
+func startResumeGame(t *testing.T, grace time.Duration) (srv *testServer, clock *ManualClock, code string, host, guest map[string]interface{}, conns []*testConn) {
+	t.Helper()
+	gm := NewGameManager(Config{ReconnectGrace: grace}, newMemoryStore())
+	clock = NewManualClock(time.Now())
+	gm.clock = clock
+	gm.dice = NewScriptedDice([]int{2, 3, 4, 5, 6})
+	srv = newTestServer(t, gm)
+
+	host = srv.post("/rooms", map[string]interface{}{"player_name": "Alice"})
+	code, _ = host["room_code"].(string)
+	guest = srv.post("/rooms/join", map[string]interface{}{"room_code": code, "player_name": "Bob"})
+	for _, p := range []map[string]interface{}{host, guest} {
+		conn, err := srv.dial(code, p, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conns = append(conns, conn)
+	}
+	conns[0].send(map[string]interface{}{"type": "GAME_START"})
+	conns[1].waitFor(t, "GAME_STARTED")
+	return srv, clock, code, host, guest, conns
+}
+
+// waitForDrop waits until the room has noticed a player's connection close
+func waitForDrop(t *testing.T, room *Room, playerID string) {
+	t.Helper()
+	waitForRoom(t, room, func() bool { return room.Players[playerID].Conn == nil })
+}
+
+func TestSeatedPlayerResumes(t *testing.T) {
+	srv, clock, code, _, guest, conns := startResumeGame(t, time.Minute)
+	room := srv.room(code)
+	guestID := guest["player_id"].(string)
+	lastSeen := int(conns[1].waitFor(t, "GAME_STARTED")["event_id"].(float64))
+
+	conns[1].Close()
+	waitForDrop(t, room, guestID)
+	clock.Advance(59 * time.Second)
+
+	// The host plays a turn while the guest is away
+	conns[0].send(map[string]interface{}{"type": "REQUEST_ROLL"})
+	conns[0].send(map[string]interface{}{"type": "CATEGORY_CHOSEN", "category": "ones"})
+	waitForRoom(t, room, func() bool { return countEvents(room, "TURN_CHANGED") == 1 })
+	room.do(func() {
+		if n := countEvents(room, "PLAYER_LEFT"); n != 0 || !slices.Contains(room.PlayerOrder, guestID) {
+			t.Errorf("guest lost their seat within the grace period: %d PLAYER_LEFT, order %v", n, room.PlayerOrder)
+		}
+	})
+
+	resumed, err := srv.dial(code, guest, fmt.Sprintf("&since=%d", lastSeen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	resumed.waitFor(t, "TURN_CHANGED")
+
+	var types []string
+	for i, msg := range resumed.messages() {
+		types = append(types, msg["type"].(string))
+		if id, _ := msg["event_id"].(float64); int(id) != lastSeen+i+1 {
+			t.Errorf("message %d is %s with event_id %v, want %d", i, msg["type"], msg["event_id"], lastSeen+i+1)
+		}
+	}
+	if want := []string{"ROLL_RESULT", "SCORE_UPDATE", "TURN_CHANGED"}; !slices.Equal(types, want) {
+		t.Errorf("resumed with %v, want only the missed %v", types, want)
+	}
+
+	// Back in their seat, the guest plays their turn
+	resumed.send(map[string]interface{}{"type": "REQUEST_ROLL"})
+	waitForRoom(t, room, func() bool { return room.RollNumber == 1 })
+	for _, msg := range resumed.messages() {
+		if msg["type"] == "ERROR" {
+			t.Errorf("resumed guest got %v", msg)
+		}
+	}
+}
+
+func TestDroppedPlayerLeavesAfterGrace(t *testing.T) {
+	srv, clock, code, _, guest, conns := startResumeGame(t, time.Minute)
+	guestID := guest["player_id"].(string)
+
+	conns[1].Close()
+	waitForDrop(t, srv.room(code), guestID)
+	clock.Advance(time.Minute)
+
+	// The host is left alone, so the room ends too
+	left := conns[0].waitFor(t, "PLAYER_LEFT")
+	if left["player_id"] != guestID {
+		t.Errorf("PLAYER_LEFT = %v, want the guest", left)
+	}
+	conns[0].waitFor(t, "ROOM_ENDED")
+}
@@ -36,10 +36,21 @@ func main() {
 		port = "8080"
 	}
 
+	config := LoadConfig()
+
 	log.Info().
 		Str("port", port).
 		Str("log_level", level.String()).
+		Dur("turn_timeout", config.TurnTimeout).
+		Dur("heartbeat_timeout", config.HeartbeatTimeout).
+		Dur("reconnect_grace", config.ReconnectGrace).
+		Dur("token_ttl", config.TokenTTL).
+		Dur("guest_token_ttl", config.GuestTTL).
+		Str("store_path", config.StorePath).
 		Msg("Starting Yahtzee server")
+	if len(config.TokenKey) == 0 {
+		log.Warn().Msg("TOKEN_SIGNING_KEY is not set, session tokens won't survive a restart")
+	}
 
 	r := chi.NewRouter()
 
@@ -56,16 +67,39 @@ func main() {
 		MaxAge:           300,
 	}))
 
+	// Open the room store and bring back rooms saved before a restart
+	store, err := openRoomStore(config.StorePath)
+	if err != nil {
+		log.Fatal().Err(err).Str("store_path", config.StorePath).Msg("Failed to open room store")
+	}
+	defer store.Close()
+
 	// Initialize game manager
-	gm := NewGameManager()
+	gm := NewGameManager(config, store)
+	if err := gm.LoadRooms(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load saved rooms")
+	}
 
 	// Start cleanup goroutine
 	go gm.CleanupExpiredRooms(30 * time.Minute)
 
 	// Routes
+	r.Get("/rooms", gm.ListRooms)
 	r.Post("/rooms", gm.CreateRoom)
 	r.Post("/rooms/join", gm.JoinRoom)
+	r.Post("/rooms/{roomCode}/tickets", gm.CreateTicket)
 	r.Get("/rooms/{roomCode}/ws", gm.WebSocket)
+	r.Post("/rooms/{roomCode}/events", gm.PostEvent)
+	r.Get("/rooms/{roomCode}/events", gm.PollEvents)
+	r.Post("/accounts", gm.Register)
+	r.Post("/accounts/login", gm.Login)
+	r.Get("/accounts/me", gm.GetAccount)
+	r.Post("/guests", gm.CreateGuest)
+	r.Get("/guests/me", gm.GetGuest)
+	r.Post("/guests/upgrade", gm.UpgradeGuest)
+	r.Post("/games/import", gm.ImportRecord)
+	r.Get("/games/{gameID}", gm.GetGame)
+	r.Get("/games/{gameID}/record", gm.ExportRecord)
 
 	// Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// longPollTimeout is how long GET /rooms/{code}/events waits for a new
+// event before returning an empty batch. It stays under the router's
+// request timeout.
+const longPollTimeout = 25 * time.Second
+
+// PostEvent handles POST /rooms/{roomCode}/events?protocol=V, the
+// long-poll counterpart of sending a command over the WebSocket
+func (gm *GameManager) PostEvent(w http.ResponseWriter, r *http.Request) {
+	roomCode := chi.URLParam(r, "roomCode")
+	if err := checkProtocol(r.URL.Query().Get("protocol")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		PlayerID string          `json:"player_id"`
+		Token    string          `json:"token"`
+		Event    json.RawMessage `json:"event"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	cmd, header, err := decodeCommand(req.Event)
+	if err != nil {
+		_, reply := commandResult(header.Type, header.RequestID, err, 0)
+		writeEvent(w, http.StatusBadRequest, "ERROR", reply)
+		return
+	}
+	claims, tokenErr := gm.verifyToken(roomCode, req.PlayerID, req.Token)
+
+	gm.mutex.RLock()
+	room, exists := gm.rooms[roomCode]
+	gm.mutex.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	var (
+		authorized  bool
+		lastEventID int
+		cmdErr      error
+	)
+	ok := room.do(func() {
+		if tokenErr != nil {
+			return
+		}
+		player := room.authenticate(claims)
+		if player == nil {
+			return
+		}
+		authorized = true
+
+		cmdErr = gm.handlePlayerCommand(room, player, cmd)
+		lastEventID = len(room.Events)
+	})
+	if !ok {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	if !authorized {
+		log.Warn().
+			Str("room_code", roomCode).
+			Str("player_id", req.PlayerID).
+			Msg("Event post unauthorized")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	eventType, reply := commandResult(cmd.commandType(), cmd.requestID(), cmdErr, lastEventID)
+	switch {
+	case cmdErr != nil:
+		writeEvent(w, http.StatusConflict, eventType, reply)
+	case reply != nil:
+		writeEvent(w, http.StatusOK, eventType, reply)
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"last_event_id": lastEventID,
+		})
+	}
+}
+
+// writeEvent answers a request with a single unrecorded event, as an ERROR
+// or ACK reply to a posted command
+func writeEvent(w http.ResponseWriter, status int, eventType string, event ServerEvent) {
+	event.header().Type = eventType
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(event)
+}
+
+// PollEvents handles GET /rooms/{roomCode}/events?since=N&protocol=V, with
+// the player's token as a bearer token. It returns every recorded event
+// with an ID above since, waiting up to longPollTimeout for one to arrive
+// if there are none yet.
+func (gm *GameManager) PollEvents(w http.ResponseWriter, r *http.Request) {
+	roomCode := chi.URLParam(r, "roomCode")
+	claims, tokenErr := gm.verifyToken(roomCode, "", bearerToken(r))
+	if err := checkProtocol(r.URL.Query().Get("protocol")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	since := 0
+	if value := r.URL.Query().Get("since"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = n
+	}
+
+	gm.mutex.RLock()
+	room, exists := gm.rooms[roomCode]
+	gm.mutex.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	timeout := time.NewTimer(longPollTimeout)
+	defer timeout.Stop()
+
+	for {
+		var (
+			authorized bool
+			response   []byte
+			wait       <-chan struct{}
+		)
+		ok := room.do(func() {
+			if tokenErr != nil {
+				return
+			}
+			player := room.authenticate(claims)
+			if player == nil {
+				return
+			}
+			authorized = true
+			player.LastSeen = gm.clock.Now()
+
+			// Answer at once if there's news, or if the client is ahead of
+			// the room and needs to resync
+			if since == len(room.Events) {
+				wait = room.newEvents
+				return
+			}
+			events := []GameEvent{}
+			if since < len(room.Events) {
+				events = clientEvents(room.Events[since:])
+			}
+			response, _ = json.Marshal(map[string]interface{}{
+				"events":        events,
+				"last_event_id": len(room.Events),
+			})
+		})
+		if !ok {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+		if !authorized {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if response != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(response)
+			return
+		}
+
+		select {
+		case <-wait:
+		case <-timeout.C:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"events":        []GameEvent{},
+				"last_event_id": since,
+			})
+			return
+		case <-r.Context().Done():
+			return
+		case <-room.done:
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ProtocolVersion is the wire protocol this server speaks. Clients send
+// it as the protocol parameter when they connect.
+const ProtocolVersion = 1
+
+var (
+	errProtocolRequired = errors.New("protocol version required")
+	errInvalidMessage   = errors.New("invalid message")
+	errUnknownCommand   = errors.New("unknown command type")
+)
+
+// checkProtocol rejects a client protocol version the server can't speak
+func checkProtocol(value string) error {
+	if value == "" {
+		return errProtocolRequired
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %q, server speaks %d", value, ProtocolVersion)
+	}
+	return nil
+}
+
+// maxActionIDLength caps the action IDs the server remembers per player
+const maxActionIDLength = 64
+
+// Command is a decoded message from a client
+type Command interface {
+	commandType() string
+	requestID() string
+	actionID() string
+	target() (turn, roll *int)
+}
+
+// commandHeader holds the fields every client command carries
+type commandHeader struct {
+	Type      string `json:"type"`
+	PlayerID  string `json:"player_id,omitempty"`  // Ignored: commands always act for the sender
+	RequestID string `json:"request_id,omitempty"` // Echoed back in the ACK or ERROR reply
+	ActionID  string `json:"action_id,omitempty"`  // Repeats of an action ID are applied once
+	Turn      *int   `json:"turn,omitempty"`       // Turn the command is meant for, if given
+	Roll      *int   `json:"roll,omitempty"`       // Roll within that turn, if given
+}
+
+func (h commandHeader) commandType() string       { return h.Type }
+func (h commandHeader) requestID() string         { return h.RequestID }
+func (h commandHeader) actionID() string          { return h.ActionID }
+func (h commandHeader) target() (turn, roll *int) { return h.Turn, h.Roll }
+
+// PlayerReadyCommand toggles a player's ready flag in the lobby
+type PlayerReadyCommand struct {
+	commandHeader
+	Ready bool `json:"ready"`
+}
+
+// StartGameCommand starts the game; only the host may send it
+type StartGameCommand struct {
+	commandHeader
+}
+
+// RequestRollCommand rolls every die not listed in HeldIndices
+type RequestRollCommand struct {
+	commandHeader
+	HeldIndices []int  `json:"held_indices"`
+	ClientNonce string `json:"client_nonce"` // Mixed into provably fair rolls
+}
+
+// CategoryChosenCommand scores the current dice in a category
+type CategoryChosenCommand struct {
+	commandHeader
+	Category string `json:"category"`
+}
+
+// EndTurnCommand ends a turn by scratching a category
+type EndTurnCommand struct {
+	commandHeader
+	Category string `json:"category"`
+}
+
+// SettingsUpdateCommand changes room settings; omitted fields are kept
+type SettingsUpdateCommand struct {
+	commandHeader
+	Settings json.RawMessage `json:"settings"`
+}
+
+// ChatMessageCommand posts a chat message to the room
+type ChatMessageCommand struct {
+	commandHeader
+	Message string `json:"message"`
+}
+
+// newCommand returns an empty command for a wire type
+func newCommand(commandType string) (Command, bool) {
+	switch commandType {
+	case "PLAYER_READY":
+		return &PlayerReadyCommand{}, true
+	case "GAME_START", "START_GAME":
+		return &StartGameCommand{}, true
+	case "REQUEST_ROLL":
+		return &RequestRollCommand{}, true
+	case "CATEGORY_CHOSEN":
+		return &CategoryChosenCommand{}, true
+	case "REQUEST_END_TURN":
+		return &EndTurnCommand{}, true
+	case "ROOM_SETTINGS_UPDATE":
+		return &SettingsUpdateCommand{}, true
+	case "CHAT_MESSAGE":
+		return &ChatMessageCommand{}, true
+	}
+	return nil, false
+}
+
+// decodeCommand strictly decodes a client message, either bare or wrapped
+// as {"type": "event", "event": {...}}. Unknown fields are an error. When
+// decoding fails, the returned header holds whatever could be read of the
+// command's type and request_id so the error can still be answered.
+func decodeCommand(data []byte) (Command, commandHeader, error) {
+	var header commandHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, header, fmt.Errorf("%w: %v", errInvalidMessage, err)
+	}
+
+	if header.Type == "event" {
+		var envelope struct {
+			Type  string          `json:"type"`
+			Event json.RawMessage `json:"event"`
+		}
+		if err := decodeStrict(data, &envelope); err != nil {
+			return nil, header, fmt.Errorf("%w: %v", errInvalidMessage, err)
+		}
+		data = envelope.Event
+		header = commandHeader{}
+		if err := json.Unmarshal(data, &header); err != nil {
+			return nil, header, fmt.Errorf("%w: %v", errInvalidMessage, err)
+		}
+	}
+
+	cmd, ok := newCommand(header.Type)
+	if !ok {
+		return nil, header, fmt.Errorf("%w %q", errUnknownCommand, header.Type)
+	}
+	if err := decodeStrict(data, cmd); err != nil {
+		return nil, header, fmt.Errorf("%w: %v", errInvalidMessage, err)
+	}
+	if len(header.ActionID) > maxActionIDLength {
+		return nil, header, fmt.Errorf("%w: action_id is longer than %d characters", errInvalidMessage, maxActionIDLength)
+	}
+	return cmd, header, nil
+}
+
+// decodeStrict unmarshals JSON, rejecting fields v doesn't have
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+)
+
+// roomCommandBuffer is how many commands can queue for a room before
+// senders block
+const roomCommandBuffer = 64
+
+// newRoom creates a room and starts its event loop. Every read or write of
+// room state must go through room.do once the room is published in
+// gm.rooms. Until then nothing else can reach it, so whoever created it can
+// set it up directly.
+func newRoom(code string, dice DiceSource, clock Clock, store RoomStore) *Room {
+	room := &Room{
+		Code:         code,
+		Players:      make(map[string]*Player),
+		Events:       []GameEvent{},
+		tickets:      make(map[string]connectTicket),
+		LastActivity: clock.Now(),
+		dice:         dice,
+		clock:        clock,
+		store:        store,
+		newEvents:    make(chan struct{}),
+		commands:     make(chan func(), roomCommandBuffer),
+		done:         make(chan struct{}),
+	}
+	go room.run()
+	return room
+}
+
+// run is the room's event loop. It executes commands one at a time so game
+// state never needs locking.
+func (room *Room) run() {
+	for {
+		select {
+		case cmd := <-room.commands:
+			room.execute(cmd)
+			room.persist()
+		case <-room.done:
+			return
+		}
+	}
+}
+
+// execute runs a single command, keeping the loop alive if it panics
+func (room *Room) execute(cmd func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("room_code", room.Code).
+				Interface("panic", r).
+				Bytes("stack", debug.Stack()).
+				Msg("Room command panicked")
+		}
+	}()
+	cmd()
+}
+
+// persist appends any new events to the room's saved log, along with a
+// snapshot every snapshotInterval events. Closed rooms are never saved, so
+// a removed room can't come back.
+func (room *Room) persist() {
+	if room.store == nil || room.closed() || room.savedEvents == len(room.Events) {
+		return
+	}
+
+	events := room.Events[room.savedEvents:]
+	var snapshot *RoomState
+	if room.snapshotDue(events) {
+		state := room.snapshot()
+		snapshot = &state
+	}
+	if err := room.store.SaveRoom(room.Code, events, snapshot); err != nil {
+		log.Error().
+			Err(err).
+			Str("room_code", room.Code).
+			Msg("Failed to save room")
+		return
+	}
+	room.savedEvents = len(room.Events)
+	if snapshot != nil {
+		room.snapshotEvents = snapshot.LastEventID
+	}
+}
+
+// snapshotDue reports whether saving events should also save a snapshot:
+// once enough events have built up since the last one, or when a provably
+// fair game starts, since its secret seed isn't in any event yet
+func (room *Room) snapshotDue(events []GameEvent) bool {
+	if len(room.Events)-room.snapshotEvents >= snapshotInterval {
+		return true
+	}
+	for _, evt := range events {
+		if evt.Type == "GAME_STARTED" && room.FairSeed != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// do runs fn on the room's event loop and waits for it to finish. It
+// returns false without running fn if the room has been closed. fn must
+// not call do itself.
+func (room *Room) do(fn func()) bool {
+	finished := make(chan struct{})
+	cmd := func() {
+		defer close(finished)
+		fn()
+	}
+
+	select {
+	case room.commands <- cmd:
+	case <-room.done:
+		return false
+	}
+
+	// The loop may have stopped between accepting and running the command
+	select {
+	case <-finished:
+		return true
+	case <-room.done:
+		select {
+		case <-finished:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// close stops the room's event loop. Commands already waiting are dropped.
+func (room *Room) close() {
+	room.closeOnce.Do(func() {
+		close(room.done)
+	})
+}
+
+// closed reports whether the room's event loop has stopped
+func (room *Room) closed() bool {
+	select {
+	case <-room.done:
+		return true
+	default:
+		return false
+	}
+}
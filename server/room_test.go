@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+// testServer serves a manager's routes, as main does
+type testServer struct {
+	*httptest.Server
+	gm *GameManager
+	t  *testing.T
+}
+
+// newTestServer serves gm, or a manager with an in-memory store when gm
+// is nil
+func newTestServer(t *testing.T, gm *GameManager) *testServer {
+	if gm == nil {
+		gm = NewGameManager(Config{}, newMemoryStore())
+	}
+	r := chi.NewRouter()
+	r.Get("/rooms", gm.ListRooms)
+	r.Post("/rooms", gm.CreateRoom)
+	r.Post("/rooms/join", gm.JoinRoom)
+	r.Post("/rooms/{roomCode}/tickets", gm.CreateTicket)
+	r.Get("/rooms/{roomCode}/ws", gm.WebSocket)
+	r.Post("/rooms/{roomCode}/events", gm.PostEvent)
+	r.Get("/rooms/{roomCode}/events", gm.PollEvents)
+	r.Post("/accounts", gm.Register)
+	r.Post("/accounts/login", gm.Login)
+	r.Get("/accounts/me", gm.GetAccount)
+	r.Post("/guests", gm.CreateGuest)
+	r.Get("/guests/me", gm.GetGuest)
+	r.Post("/guests/upgrade", gm.UpgradeGuest)
+	r.Get("/games/{gameID}", gm.GetGame)
+
+	srv := &testServer{Server: httptest.NewServer(r), gm: gm, t: t}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// request sends body as JSON, with bearer as the Authorization token when
+// it isn't empty, and decodes the JSON reply
+func (srv *testServer) request(method, path, bearer string, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, srv.URL+path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		srv.t.Error(err)
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	var reply map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&reply)
+	return resp.StatusCode, reply
+}
+
+// post sends body as JSON and decodes the JSON reply
+func (srv *testServer) post(path string, body interface{}) map[string]interface{} {
+	_, reply := srv.request(http.MethodPost, path, "", body)
+	return reply
+}
+
+// command posts a player's command to their room and returns the ERROR
+// code it was rejected with, or ""
+func (srv *testServer) command(code string, player map[string]interface{}, event map[string]interface{}) string {
+	status, reply := srv.request(http.MethodPost, fmt.Sprintf("/rooms/%s/events?protocol=%d", code, ProtocolVersion), "", map[string]interface{}{
+		"player_id": player["player_id"],
+		"token":     player["token"],
+		"event":     event,
+	})
+	if status != http.StatusOK && status != http.StatusConflict {
+		return http.StatusText(status)
+	}
+	errCode, _ := reply["code"].(string)
+	return errCode
+}
+
+// room returns the manager's room with a code, or nil
+func (srv *testServer) room(code string) *Room {
+	srv.gm.mutex.RLock()
+	defer srv.gm.mutex.RUnlock()
+	return srv.gm.rooms[code]
+}
+
+// testConn is a player's WebSocket that records every message it receives
+type testConn struct {
+	*websocket.Conn
+	mutex      sync.Mutex
+	writeMutex sync.Mutex
+	received   []map[string]interface{}
+}
+
+// dial connects a player's WebSocket, adding query to its URL, and records
+// what it receives in the background
+func (srv *testServer) dial(code string, player map[string]interface{}, query string) (*testConn, error) {
+	ticket := srv.post("/rooms/"+code+"/tickets", map[string]interface{}{
+		"player_id": player["player_id"],
+		"token":     player["token"],
+	})
+	url := fmt.Sprintf("ws%s/rooms/%s/ws?protocol=%d&ticket=%v%s",
+		strings.TrimPrefix(srv.URL, "http"), code, ProtocolVersion, ticket["ticket"], query)
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &testConn{Conn: ws}
+	go func() {
+		for {
+			var msg map[string]interface{}
+			if err := ws.ReadJSON(&msg); err != nil {
+				return
+			}
+			conn.mutex.Lock()
+			conn.received = append(conn.received, msg)
+			conn.mutex.Unlock()
+		}
+	}()
+	return conn, nil
+}
+
+// send writes a command to the connection
+func (c *testConn) send(event map[string]interface{}) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.WriteJSON(map[string]interface{}{"type": "event", "event": event})
+}
+
+// messages returns what the connection has received so far
+func (c *testConn) messages() []map[string]interface{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]map[string]interface{}(nil), c.received...)
+}
+
+// countMessages returns how many messages of a type a connection has
+// received
+func countMessages(c *testConn, eventType string) int {
+	count := 0
+	for _, msg := range c.messages() {
+		if msg["type"] == eventType {
+			count++
+		}
+	}
+	return count
+}
+
+// waitFor waits until the connection has received a message of a type,
+// and returns it
+func (c *testConn) waitFor(t *testing.T, eventType string) map[string]interface{} {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, msg := range c.messages() {
+			if msg["type"] == eventType {
+				return msg
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no %s arrived", eventType)
+	return nil
+}
+
+// TestRoomConcurrentClients drives one room from many goroutines at once:
+// players send turn commands and chat, new players join and leave, seated
+// players drop and reconnect, and turns time out. Run it with -race.
+func TestRoomConcurrentClients(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	host := srv.post("/rooms", map[string]interface{}{"player_name": "Host"})
+	code, _ := host["room_code"].(string)
+	players := []map[string]interface{}{host}
+	for i := 0; i < 3; i++ {
+		players = append(players, srv.post("/rooms/join", map[string]interface{}{
+			"room_code":   code,
+			"player_name": "Player " + strconv.Itoa(i+1),
+		}))
+	}
+
+	conns := make([]*testConn, len(players))
+	for i, player := range players {
+		conn, err := srv.dial(code, player, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = conn
+	}
+	send := func(i int, event map[string]interface{}) { conns[i].send(event) }
+
+	room := srv.room(code)
+	// Far below what settings allow, so timeouts race the players
+	room.do(func() { room.TurnTimeout = 20 * time.Millisecond })
+	send(0, map[string]interface{}{"type": "GAME_START"})
+
+	var wg sync.WaitGroup
+	const rounds = 50
+	for i := range conns[:3] {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				send(i, map[string]interface{}{"type": "REQUEST_ROLL", "held_indices": []int{0}})
+				send(i, map[string]interface{}{"type": "CATEGORY_CHOSEN", "category": classicCategories[j%len(classicCategories)]})
+				send(i, map[string]interface{}{"type": "CHAT_MESSAGE", "message": fmt.Sprintf("hi %d", j)})
+			}
+		}(i)
+	}
+
+	// The last player drops and rejoins
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		conns[3].Close()
+		for j := 0; j < 5; j++ {
+			rejoined := srv.post("/rooms/join", map[string]interface{}{
+				"room_code": code,
+				"player_id": players[3]["player_id"],
+				"token":     players[3]["token"],
+			})
+			conn, err := srv.dial(code, rejoined, "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+			conn.Close()
+		}
+	}()
+
+	// Newcomers join and leave while the game runs
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 5; j++ {
+			joined := srv.post("/rooms/join", map[string]interface{}{"room_code": code, "player_name": "Visitor"})
+			if joined["player_id"] == nil {
+				continue
+			}
+			conn, err := srv.dial(code, joined, "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+			conn.Close()
+		}
+	}()
+
+	// Room listings read room state from outside the loop
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			if resp, err := http.Get(srv.URL + "/rooms"); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	// Everyone who stayed hears every chat message. Turn commands sent out
+	// of turn are rejected, but nothing else may be.
+	for i, conn := range conns[:3] {
+		deadline := time.Now().Add(2 * time.Second)
+		for countMessages(conn, "CHAT_MESSAGE") < 3*rounds && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := countMessages(conn, "CHAT_MESSAGE"); n != 3*rounds {
+			t.Errorf("player %d got %d chat messages, want %d", i, n, 3*rounds)
+		}
+		for _, msg := range conn.messages() {
+			if msg["type"] == "ERROR" && (msg["command_type"] == "CHAT_MESSAGE" || msg["code"] == CodeInvalidMessage) {
+				t.Errorf("player %d got %v", i, msg)
+			}
+		}
+		conn.Close()
+	}
+
+	room.do(func() {
+		if n := countEvents(room, "CHAT_MESSAGE"); n != 3*rounds {
+			t.Errorf("room recorded %d chat messages, want %d", n, 3*rounds)
+		}
+		for i, e := range room.Events {
+			if e.ID != i+1 {
+				t.Errorf("event %d has ID %d", i+1, e.ID)
+				break
+			}
+		}
+		if len(room.PlayerOrder) > 0 && room.CurrentPlayerIdx >= len(room.PlayerOrder) {
+			t.Errorf("current player %d is past the %d seated players", room.CurrentPlayerIdx, len(room.PlayerOrder))
+		}
+	})
+}
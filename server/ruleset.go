@@ -0,0 +1,70 @@
+package main
+
+import "strings"
+
+// Ruleset defines the rules of a dice game variant: its scorecard,
+// dice, rolls and how categories and bonuses are scored
+type Ruleset interface {
+	// Name returns the variant identifier used by clients
+	Name() string
+	// Categories returns the scorecard categories in display order
+	Categories() []string
+	// DiceCount returns the number of dice rolled each turn
+	DiceCount() int
+	// RollsPerTurn returns how many rolls a player gets each turn
+	RollsPerTurn() int
+	// SavesUnusedRolls reports whether rolls left at the end of a turn
+	// carry over to the player's later turns
+	SavesUnusedRolls() bool
+	// ScoreTurn validates a category choice against a player's scorecard and
+	// returns the points for the category plus any bonus earned this turn
+	ScoreTurn(scores map[string]int, category string, dice []int) (score int, bonus int, err error)
+	// UpperBonus returns the end-of-game bonus earned by the upper section
+	UpperBonus(scores map[string]int) int
+}
+
+// DefaultVariant is used when a room is created without a variant
+const DefaultVariant = "yahtzee"
+
+// rulesets holds every variant a room can be created with
+var rulesets = map[string]Ruleset{
+	DefaultVariant: ClassicRuleset{},
+}
+
+// LookupRuleset returns the ruleset registered for a variant name.
+// An empty name selects the default variant.
+func LookupRuleset(variant string) (Ruleset, bool) {
+	if variant == "" {
+		variant = DefaultVariant
+	}
+	rules, ok := rulesets[strings.ToLower(variant)]
+	return rules, ok
+}
+
+// hasCategory reports whether category is on the ruleset's scorecard
+func hasCategory(rules Ruleset, category string) bool {
+	for _, c := range rules.Categories() {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// newDice returns a dice slice sized for the ruleset, filled with value
+func newDice(rules Ruleset, value int) []int {
+	dice := make([]int, rules.DiceCount())
+	for i := range dice {
+		dice[i] = value
+	}
+	return dice
+}
+
+// upperTotal sums the upper section of a scorecard
+func upperTotal(scores map[string]int) int {
+	total := 0
+	for _, cat := range upperCategoryForFace {
+		total += scores[cat]
+	}
+	return total
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	errUnknownCategory    = errors.New("unknown category")
+	errCategoryTaken      = errors.New("category already taken")
+	errJokerUpperRequired = errors.New("joker rules require the matching upper category")
+	errJokerLowerRequired = errors.New("joker rules require an open lower category")
+)
+
+// upperCategoryForFace maps a die face to its upper section category
+var upperCategoryForFace = map[int]string{
+	1: "ones", 2: "twos", 3: "threes", 4: "fours", 5: "fives", 6: "sixes",
+}
+
+// isUpperCategory reports whether category belongs to the upper section
+func isUpperCategory(category string) bool {
+	for _, c := range upperCategoryForFace {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// allRolled reports whether every die shows a face from 1 to 6
+func allRolled(dice []int) bool {
+	for _, d := range dice {
+		if d < 1 || d > 6 {
+			return false
+		}
+	}
+	return len(dice) > 0
+}
+
+// countFaces returns how many times each face value appears in dice
+func countFaces(dice []int) map[int]int {
+	counts := make(map[int]int)
+	for _, d := range dice {
+		counts[d]++
+	}
+	return counts
+}
+
+// sumDice returns the total of all dice
+func sumDice(dice []int) int {
+	total := 0
+	for _, d := range dice {
+		total += d
+	}
+	return total
+}
+
+// hasNOfAKind reports whether at least n dice share the same face
+func hasNOfAKind(dice []int, n int) bool {
+	for _, c := range countFaces(dice) {
+		if c >= n {
+			return true
+		}
+	}
+	return false
+}
+
+// isFullHouse reports whether dice are exactly three of one face and two of another
+func isFullHouse(dice []int) bool {
+	counts := make([]int, 0, 2)
+	for _, c := range countFaces(dice) {
+		counts = append(counts, c)
+	}
+	sort.Ints(counts)
+	return len(counts) == 2 && counts[0] == 2 && counts[1] == 3
+}
+
+// hasRun reports whether dice contain every face in run
+func hasRun(dice []int, run ...int) bool {
+	counts := countFaces(dice)
+	for _, face := range run {
+		if counts[face] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// scoreUpper returns the sum of dice showing the face for an upper category
+func scoreUpper(category string, dice []int) int {
+	for face, c := range upperCategoryForFace {
+		if c == category {
+			return countFaces(dice)[face] * face
+		}
+	}
+	return 0
+}
+
+// ClassicRuleset implements standard Yahtzee: five dice, three rolls,
+// a 35-point upper bonus at 63 and Joker rules for extra Yahtzees
+type ClassicRuleset struct{}
+
+var classicCategories = []string{
+	"ones", "twos", "threes", "fours", "fives", "sixes",
+	"three_of_a_kind", "four_of_a_kind", "full_house",
+	"small_straight", "large_straight", "yahtzee",
+}
+
+// Name implements Ruleset
+func (ClassicRuleset) Name() string { return DefaultVariant }
+
+// Categories implements Ruleset
+func (ClassicRuleset) Categories() []string { return classicCategories }
+
+// DiceCount implements Ruleset
+func (ClassicRuleset) DiceCount() int { return 5 }
+
+// RollsPerTurn implements Ruleset
+func (ClassicRuleset) RollsPerTurn() int { return 3 }
+
+// SavesUnusedRolls implements Ruleset
+func (ClassicRuleset) SavesUnusedRolls() bool { return false }
+
+// UpperBonus implements Ruleset
+func (ClassicRuleset) UpperBonus(scores map[string]int) int {
+	if upperTotal(scores) >= 63 {
+		return 35
+	}
+	return 0
+}
+
+// ScoreCategory computes the score the dice earn in the given category.
+// The boolean result is false for unknown categories.
+func (r ClassicRuleset) ScoreCategory(category string, dice []int) (int, bool) {
+	if !hasCategory(r, category) {
+		return 0, false
+	}
+	if !allRolled(dice) {
+		// Unrolled dice never score
+		return 0, true
+	}
+
+	switch category {
+	case "three_of_a_kind":
+		if hasNOfAKind(dice, 3) {
+			return sumDice(dice), true
+		}
+	case "four_of_a_kind":
+		if hasNOfAKind(dice, 4) {
+			return sumDice(dice), true
+		}
+	case "full_house":
+		if isFullHouse(dice) {
+			return 25, true
+		}
+	case "small_straight":
+		if hasRun(dice, 1, 2, 3, 4) || hasRun(dice, 2, 3, 4, 5) || hasRun(dice, 3, 4, 5, 6) {
+			return 30, true
+		}
+	case "large_straight":
+		if hasRun(dice, 1, 2, 3, 4, 5) || hasRun(dice, 2, 3, 4, 5, 6) {
+			return 40, true
+		}
+	case "yahtzee":
+		if hasNOfAKind(dice, 5) {
+			return 50, true
+		}
+	default:
+		return scoreUpper(category, dice), true
+	}
+	return 0, true
+}
+
+// jokerScore returns the full value of a lower category when a Yahtzee is
+// played as a joker
+func (r ClassicRuleset) jokerScore(category string, dice []int) int {
+	switch category {
+	case "full_house":
+		return 25
+	case "small_straight":
+		return 30
+	case "large_straight":
+		return 40
+	case "three_of_a_kind", "four_of_a_kind":
+		return sumDice(dice)
+	}
+	score, _ := r.ScoreCategory(category, dice)
+	return score
+}
+
+// ScoreTurn implements Ruleset.
+// A Yahtzee rolled after the Yahtzee box is filled follows the official
+// Joker rules: the matching upper box must be used if open, otherwise any
+// open lower box scores full value, otherwise an open upper box scores zero.
+func (r ClassicRuleset) ScoreTurn(scores map[string]int, category string, dice []int) (score int, bonus int, err error) {
+	if !hasCategory(r, category) {
+		return 0, 0, errUnknownCategory
+	}
+	if _, taken := scores[category]; taken {
+		return 0, 0, errCategoryTaken
+	}
+
+	yahtzeeScore, yahtzeeFilled := scores["yahtzee"]
+	if !allRolled(dice) || !hasNOfAKind(dice, 5) || !yahtzeeFilled {
+		score, _ = r.ScoreCategory(category, dice)
+		return score, 0, nil
+	}
+
+	// Extra Yahtzee: bonus only if the Yahtzee box was scored, not scratched
+	if yahtzeeScore > 0 {
+		bonus = 100
+	}
+
+	upper := upperCategoryForFace[dice[0]]
+	if _, upperTaken := scores[upper]; !upperTaken {
+		if category != upper {
+			return 0, 0, errJokerUpperRequired
+		}
+		score, _ = r.ScoreCategory(category, dice)
+		return score, bonus, nil
+	}
+
+	if isUpperCategory(category) {
+		for _, c := range r.Categories() {
+			if _, taken := scores[c]; !taken && !isUpperCategory(c) {
+				return 0, 0, errJokerLowerRequired
+			}
+		}
+		return 0, bonus, nil
+	}
+
+	return r.jokerScore(category, dice), bonus, nil
+}
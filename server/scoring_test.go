@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassicScoreCategory(t *testing.T) {
+	tests := []struct {
+		category string
+		dice     []int
+		want     int
+	}{
+		{"ones", []int{1, 1, 2, 3, 1}, 3},
+		{"threes", []int{3, 3, 3, 2, 1}, 9},
+		{"sixes", []int{1, 2, 3, 4, 5}, 0},
+		{"sixes", []int{6, 6, 6, 6, 6}, 30},
+		{"three_of_a_kind", []int{4, 4, 4, 2, 1}, 15},
+		{"three_of_a_kind", []int{4, 4, 3, 2, 1}, 0},
+		{"four_of_a_kind", []int{5, 5, 5, 5, 2}, 22},
+		{"four_of_a_kind", []int{5, 5, 5, 2, 2}, 0},
+		{"full_house", []int{2, 2, 3, 3, 3}, 25},
+		{"full_house", []int{2, 2, 2, 2, 3}, 0},
+		{"full_house", []int{4, 4, 4, 4, 4}, 0},
+		{"small_straight", []int{1, 2, 3, 4, 6}, 30},
+		{"small_straight", []int{3, 4, 5, 6, 6}, 30},
+		{"small_straight", []int{1, 2, 3, 5, 6}, 0},
+		{"large_straight", []int{2, 3, 4, 5, 6}, 40},
+		{"large_straight", []int{1, 2, 3, 4, 6}, 0},
+		{"yahtzee", []int{2, 2, 2, 2, 2}, 50},
+		{"yahtzee", []int{2, 2, 2, 2, 3}, 0},
+		{"full_house", []int{0, 0, 0, 0, 0}, 0},
+		{"yahtzee", []int{0, 0, 0, 0, 0}, 0},
+	}
+
+	rules := ClassicRuleset{}
+	for _, tt := range tests {
+		got, _ := rules.ScoreCategory(tt.category, tt.dice)
+		if got != tt.want {
+			t.Errorf("ScoreCategory(%q, %v) = %d, want %d", tt.category, tt.dice, got, tt.want)
+		}
+	}
+
+	if _, ok := rules.ScoreCategory("chance", []int{1, 2, 3, 4, 5}); ok {
+		t.Error("ScoreCategory accepted a category the classic scorecard doesn't have")
+	}
+}
+
+func TestClassicScoreTurn(t *testing.T) {
+	tests := []struct {
+		name     string
+		scores   map[string]int
+		category string
+		dice     []int
+		want     int
+		err      error
+	}{
+		{"scores the dice", nil, "full_house", []int{6, 6, 1, 1, 1}, 25, nil},
+		{"zero for no match", nil, "large_straight", []int{6, 6, 1, 1, 1}, 0, nil},
+		{"unknown category", nil, "chance", []int{6, 6, 1, 1, 1}, 0, errUnknownCategory},
+		{"taken category", map[string]int{"fours": 8}, "fours", []int{4, 4, 1, 1, 1}, 0, errCategoryTaken},
+		{"taken by a scratch", map[string]int{"fours": 0}, "fours", []int{4, 4, 1, 1, 1}, 0, errCategoryTaken},
+	}
+
+	rules := ClassicRuleset{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := tt.scores
+			if scores == nil {
+				scores = map[string]int{}
+			}
+			got, bonus, err := rules.ScoreTurn(scores, tt.category, tt.dice)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want || bonus != 0 {
+				t.Errorf("score = %d bonus %d, want %d bonus 0", got, bonus, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassicUpperBonus(t *testing.T) {
+	rules := ClassicRuleset{}
+	atThreshold := map[string]int{"ones": 3, "twos": 6, "threes": 9, "fours": 12, "fives": 15, "sixes": 18}
+	if got := rules.UpperBonus(atThreshold); got != 35 {
+		t.Errorf("UpperBonus at 63 = %d, want 35", got)
+	}
+	atThreshold["sixes"] = 12
+	if got := rules.UpperBonus(atThreshold); got != 0 {
+		t.Errorf("UpperBonus at 57 = %d, want 0", got)
+	}
+}
+
+func TestClassicJoker(t *testing.T) {
+	fours := []int{4, 4, 4, 4, 4}
+	allLowerFilled := map[string]int{
+		"fours": 16, "yahtzee": 50, "three_of_a_kind": 20, "four_of_a_kind": 20,
+		"full_house": 25, "small_straight": 30, "large_straight": 40,
+	}
+
+	tests := []struct {
+		name      string
+		scores    map[string]int
+		category  string
+		want      int
+		wantBonus int
+		err       error
+	}{
+		{"first yahtzee scores normally", map[string]int{}, "yahtzee", 50, 0, nil},
+		{"first yahtzee elsewhere is no joker", map[string]int{}, "full_house", 0, 0, nil},
+		{"upper box must come first", map[string]int{"yahtzee": 50}, "full_house", 0, 0, errJokerUpperRequired},
+		{"upper box scores with bonus", map[string]int{"yahtzee": 50}, "fours", 20, 100, nil},
+		{"joker full house", map[string]int{"yahtzee": 50, "fours": 16}, "full_house", 25, 100, nil},
+		{"joker small straight", map[string]int{"yahtzee": 50, "fours": 16}, "small_straight", 30, 100, nil},
+		{"joker large straight", map[string]int{"yahtzee": 50, "fours": 16}, "large_straight", 40, 100, nil},
+		{"joker of a kind sums", map[string]int{"yahtzee": 50, "fours": 16}, "three_of_a_kind", 20, 100, nil},
+		{"no bonus after a scratched yahtzee", map[string]int{"yahtzee": 0, "fours": 16}, "full_house", 25, 0, nil},
+		{"upper zero needs full lower boxes", map[string]int{"yahtzee": 50, "fours": 16}, "ones", 0, 0, errJokerLowerRequired},
+		{"upper zero once lower boxes are full", allLowerFilled, "ones", 0, 100, nil},
+	}
+
+	rules := ClassicRuleset{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, bonus, err := rules.ScoreTurn(tt.scores, tt.category, fours)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want || bonus != tt.wantBonus {
+				t.Errorf("score = %d bonus %d, want %d bonus %d", got, bonus, tt.want, tt.wantBonus)
+			}
+		})
+	}
+}
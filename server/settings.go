@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Limits on room settings
+const (
+	playerLimit         = 6
+	minTurnTimerSeconds = 15
+	maxTurnTimerSeconds = 600
+)
+
+var (
+	errSettingsLocked   = errors.New("settings can't change after the game has started")
+	errNotEnoughPlayers = errors.New("not enough players to start")
+	errSettingsRequired = errors.New("settings required")
+	errInvalidSettings  = errors.New("invalid settings")
+)
+
+// RoomSettings are the options a host picks for their room
+type RoomSettings struct {
+	MaxPlayers       int    `json:"max_players"`
+	MinPlayers       int    `json:"min_players"`
+	Variant          string `json:"variant"`
+	TurnTimerSeconds int    `json:"turn_timer_seconds"` // 0 disables the turn timer
+	AllowSpectators  bool   `json:"allow_spectators"`
+	Private          bool   `json:"private"`       // Private rooms are hidden from the room list
+	ProvablyFair     bool   `json:"provably_fair"` // Derive dice from a committed seed
+}
+
+// defaultSettings returns the settings used for anything a host leaves out
+func (gm *GameManager) defaultSettings() RoomSettings {
+	return RoomSettings{
+		MaxPlayers:       playerLimit,
+		MinPlayers:       2,
+		Variant:          DefaultVariant,
+		TurnTimerSeconds: int(gm.config.TurnTimeout / time.Second),
+		AllowSpectators:  true,
+		Private:          false,
+	}
+}
+
+// Validate checks the settings and normalises the variant name
+func (s *RoomSettings) Validate() error {
+	if s.MaxPlayers < 1 || s.MaxPlayers > playerLimit {
+		return fmt.Errorf("max_players must be between 1 and %d", playerLimit)
+	}
+	if s.MinPlayers < 1 || s.MinPlayers > s.MaxPlayers {
+		return errors.New("min_players must be between 1 and max_players")
+	}
+	if s.TurnTimerSeconds != 0 && (s.TurnTimerSeconds < minTurnTimerSeconds || s.TurnTimerSeconds > maxTurnTimerSeconds) {
+		return fmt.Errorf("turn_timer_seconds must be 0 or between %d and %d", minTurnTimerSeconds, maxTurnTimerSeconds)
+	}
+	rules, ok := LookupRuleset(s.Variant)
+	if !ok {
+		return fmt.Errorf("unknown variant %q", s.Variant)
+	}
+	s.Variant = rules.Name()
+	return nil
+}
+
+// applySettings stores validated settings and the rules they select
+func (room *Room) applySettings(settings RoomSettings) {
+	room.Settings = settings
+	room.Rules, _ = LookupRuleset(settings.Variant)
+	room.TurnTimeout = time.Duration(settings.TurnTimerSeconds) * time.Second
+	room.CurrentDice = newDice(room.Rules, 1)
+	room.RollsLeft = room.Rules.RollsPerTurn()
+}
+
+// handleSettingsUpdate lets the host change room settings in the lobby.
+// Fields left out of the update keep their current values.
+func (room *Room) handleSettingsUpdate(playerID string, cmd *SettingsUpdateCommand) error {
+	if room.HostID != playerID {
+		return errNotHost
+	}
+	if room.GameStarted {
+		return errSettingsLocked
+	}
+
+	if len(cmd.Settings) == 0 {
+		return errSettingsRequired
+	}
+	settings := room.Settings
+	if err := decodeStrict(cmd.Settings, &settings); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidSettings, err)
+	}
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidSettings, err)
+	}
+
+	playerCount := len(room.PlayerOrder)
+	if settings.MaxPlayers < playerCount {
+		return fmt.Errorf("%w: max_players is below the number of players already in the room", errInvalidSettings)
+	}
+
+	rules, _ := LookupRuleset(settings.Variant)
+	room.addEvent("ROOM_SETTINGS_UPDATE", &SettingsEvent{
+		Settings:   settings,
+		Categories: rules.Categories(),
+	})
+
+	log.Info().
+		Str("room_code", room.Code).
+		Interface("settings", settings).
+		Msg("Room settings updated")
+	return nil
+}
+
+// ListRooms handles GET /rooms, listing public rooms still in the lobby
+func (gm *GameManager) ListRooms(w http.ResponseWriter, r *http.Request) {
+	list := make([]map[string]interface{}, 0)
+	for _, room := range gm.snapshotRooms() {
+		var entry map[string]interface{}
+		room.do(func() {
+			if room.Settings.Private || room.GameStarted || len(room.PlayerOrder) >= room.Settings.MaxPlayers {
+				return
+			}
+
+			hostName := ""
+			if host, exists := room.Players[room.HostID]; exists {
+				hostName = host.Name
+			}
+			entry = map[string]interface{}{
+				"room_code":    room.Code,
+				"host_name":    hostName,
+				"player_count": len(room.PlayerOrder),
+				"settings":     room.Settings,
+			}
+		})
+		if entry != nil {
+			list = append(list, entry)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["room_code"].(string) < list[j]["room_code"].(string)
+	})
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rooms": list,
+	})
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// snapshotInterval is how many events a room records between snapshots.
+// Restoring a room replays at most this many events on top of one.
+const snapshotInterval = 50
+
+var errNoHistory = errors.New("event log doesn't start with ROOM_CREATED")
+
+// RoomStore saves rooms so games outlive the server process. A room is its
+// event log; snapshots only save replaying all of it. Rooms save
+// themselves after every change; see Room.persist.
+type RoomStore interface {
+	// SaveRoom appends events to a room's log and, when snapshot isn't
+	// nil, replaces its snapshot. Both happen or neither does.
+	SaveRoom(code string, events []GameEvent, snapshot *RoomState) error
+	// LoadRooms returns every saved room with its full event log
+	LoadRooms() ([]SavedRoom, error)
+	// DeleteRoom forgets a room and its event log
+	DeleteRoom(code string) error
+	// ArchiveGame saves a finished game under its ID, replacing any
+	// earlier copy
+	ArchiveGame(game ArchivedGame) error
+	// LoadGame returns an archived game, or errGameNotFound
+	LoadGame(id string) (ArchivedGame, error)
+	// CreateAccount saves a new account, or returns errUsernameTaken or,
+	// if its ID is in use, errAccountExists
+	CreateAccount(account Account) error
+	// LoadAccount returns an account by ID, or errAccountNotFound
+	LoadAccount(id string) (Account, error)
+	// FindAccount returns an account by username, ignoring case, or
+	// errAccountNotFound
+	FindAccount(username string) (Account, error)
+	// AccountGames returns the IDs of archived games an account played,
+	// oldest first
+	AccountGames(accountID string) ([]string, error)
+	// Close releases the store
+	Close() error
+}
+
+// RoomState is a snapshot of a room as of one of its events
+type RoomState struct {
+	Code             string             `json:"room_code"`
+	LastEventID      int                `json:"last_event_id"` // The snapshot includes this event and all before it
+	Players          map[string]*Player `json:"players"`
+	PlayerOrder      []string           `json:"player_order"`
+	CurrentPlayerIdx int                `json:"current_player_idx"`
+	CurrentDice      []int              `json:"current_dice"`
+	RollsLeft        int                `json:"rolls_left"`
+	GameStarted      bool               `json:"game_started"`
+	Phase            TurnPhase          `json:"phase"`
+	TurnNumber       int                `json:"turn_number"`
+	RollNumber       int                `json:"roll_number"`
+	TurnDeadline     time.Time          `json:"turn_deadline"`
+	HostID           string             `json:"host_id"`
+	Settings         RoomSettings       `json:"settings"`
+	FairSeed         string             `json:"fair_seed,omitempty"`
+	FairRollCount    int                `json:"fair_roll_count"`
+}
+
+// SavedRoom is a room as loaded back from a store
+type SavedRoom struct {
+	Code     string
+	Snapshot *RoomState // nil until the room's first snapshot
+	Events   []GameEvent
+}
+
+// openRoomStore opens the on-disk store at path, or an in-memory store
+// when path is empty
+func openRoomStore(path string) (RoomStore, error) {
+	if path == "" {
+		return newMemoryStore(), nil
+	}
+	return openBoltStore(path)
+}
+
+// memoryStore keeps rooms in memory, encoded the same way as on disk so it
+// behaves like a real store. Nothing survives a restart.
+type memoryStore struct {
+	mutex     sync.Mutex
+	snapshots map[string][]byte
+	events    map[string][][]byte
+	games     map[string][]byte
+	accounts  map[string][]byte
+	usernames map[string]string               // usernameKey to account ID
+	played    map[string]map[string]time.Time // Account ID to game IDs and when they finished
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		snapshots: make(map[string][]byte),
+		events:    make(map[string][][]byte),
+		games:     make(map[string][]byte),
+		accounts:  make(map[string][]byte),
+		usernames: make(map[string]string),
+		played:    make(map[string]map[string]time.Time),
+	}
+}
+
+func (s *memoryStore) SaveRoom(code string, events []GameEvent, snapshot *RoomState) error {
+	var data []byte
+	if snapshot != nil {
+		var err error
+		if data, err = json.Marshal(snapshot); err != nil {
+			return err
+		}
+	}
+	encoded := make([][]byte, 0, len(events))
+	for _, evt := range events {
+		e, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, e)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events[code] = append(s.events[code], encoded...)
+	if data != nil {
+		s.snapshots[code] = data
+	}
+	return nil
+}
+
+func (s *memoryStore) LoadRooms() ([]SavedRoom, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	codes := make([]string, 0, len(s.events))
+	for code := range s.events {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	rooms := make([]SavedRoom, 0, len(codes))
+	for _, code := range codes {
+		saved := SavedRoom{Code: code}
+		if data, exists := s.snapshots[code]; exists {
+			saved.Snapshot = &RoomState{}
+			if err := json.Unmarshal(data, saved.Snapshot); err != nil {
+				return nil, err
+			}
+		}
+		for _, data := range s.events[code] {
+			var evt GameEvent
+			if err := json.Unmarshal(data, &evt); err != nil {
+				return nil, err
+			}
+			saved.Events = append(saved.Events, evt)
+		}
+		rooms = append(rooms, saved)
+	}
+	return rooms, nil
+}
+
+func (s *memoryStore) DeleteRoom(code string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.snapshots, code)
+	delete(s.events, code)
+	return nil
+}
+
+func (s *memoryStore) ArchiveGame(game ArchivedGame) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.games[game.ID] = data
+	for _, p := range game.Players {
+		if p.AccountID == "" {
+			continue
+		}
+		if s.played[p.AccountID] == nil {
+			s.played[p.AccountID] = make(map[string]time.Time)
+		}
+		s.played[p.AccountID][game.ID] = game.FinishedAt
+	}
+	return nil
+}
+
+func (s *memoryStore) LoadGame(id string) (ArchivedGame, error) {
+	var game ArchivedGame
+	s.mutex.Lock()
+	data, exists := s.games[id]
+	s.mutex.Unlock()
+	if !exists {
+		return game, errGameNotFound
+	}
+	err := json.Unmarshal(data, &game)
+	return game, err
+}
+
+func (s *memoryStore) CreateAccount(account Account) error {
+	data, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.accounts[account.ID]; exists {
+		return errAccountExists
+	}
+	key := usernameKey(account.Username)
+	if _, taken := s.usernames[key]; taken {
+		return errUsernameTaken
+	}
+	s.usernames[key] = account.ID
+	s.accounts[account.ID] = data
+	return nil
+}
+
+func (s *memoryStore) LoadAccount(id string) (Account, error) {
+	var account Account
+	s.mutex.Lock()
+	data, exists := s.accounts[id]
+	s.mutex.Unlock()
+	if !exists {
+		return account, errAccountNotFound
+	}
+	err := json.Unmarshal(data, &account)
+	return account, err
+}
+
+func (s *memoryStore) FindAccount(username string) (Account, error) {
+	s.mutex.Lock()
+	id, exists := s.usernames[usernameKey(username)]
+	s.mutex.Unlock()
+	if !exists {
+		return Account{}, errAccountNotFound
+	}
+	return s.LoadAccount(id)
+}
+
+func (s *memoryStore) AccountGames(accountID string) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return gamesByFinish(s.played[accountID]), nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// gamesByFinish orders game IDs by when the games finished, oldest first
+func gamesByFinish(finished map[string]time.Time) []string {
+	ids := make([]string, 0, len(finished))
+	for id := range finished {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := finished[ids[i]], finished[ids[j]]
+		if ti.Equal(tj) {
+			return ids[i] < ids[j]
+		}
+		return ti.Before(tj)
+	})
+	return ids
+}
+
+// snapshot captures the room as of its latest event. It runs on the
+// room's event loop.
+func (room *Room) snapshot() RoomState {
+	return RoomState{
+		Code:             room.Code,
+		LastEventID:      len(room.Events),
+		Players:          room.Players,
+		PlayerOrder:      room.PlayerOrder,
+		CurrentPlayerIdx: room.CurrentPlayerIdx,
+		CurrentDice:      room.CurrentDice,
+		RollsLeft:        room.RollsLeft,
+		GameStarted:      room.GameStarted,
+		Phase:            room.Phase,
+		TurnNumber:       room.TurnNumber,
+		RollNumber:       room.RollNumber,
+		TurnDeadline:     room.TurnDeadline,
+		HostID:           room.HostID,
+		Settings:         room.Settings,
+		FairSeed:         room.FairSeed,
+		FairRollCount:    room.FairRollCount,
+	}
+}
+
+// restoreSnapshot loads a snapshot into a new room
+func (room *Room) restoreSnapshot(state RoomState) {
+	room.applySettings(state.Settings)
+
+	room.Players = state.Players
+	for _, player := range room.Players {
+		if player.Scores == nil {
+			player.Scores = make(map[string]int)
+		}
+	}
+	room.PlayerOrder = state.PlayerOrder
+	room.CurrentPlayerIdx = state.CurrentPlayerIdx
+	room.CurrentDice = state.CurrentDice
+	room.RollsLeft = state.RollsLeft
+	room.GameStarted = state.GameStarted
+	room.Phase = state.Phase
+	room.TurnNumber = state.TurnNumber
+	room.RollNumber = state.RollNumber
+	room.TurnDeadline = state.TurnDeadline
+	room.HostID = state.HostID
+	room.FairSeed = state.FairSeed
+	room.FairRollCount = state.FairRollCount
+}
+
+// rebuild restores a saved room from its latest snapshot and the events
+// recorded after it, or from its whole event log when it has no snapshot,
+// then resumes its turn timer. It runs before the room is published; see
+// newRoom.
+func (room *Room) rebuild(saved SavedRoom) error {
+	replay := saved.Events
+	if saved.Snapshot != nil && saved.Snapshot.LastEventID <= len(saved.Events) {
+		room.restoreSnapshot(*saved.Snapshot)
+		room.snapshotEvents = saved.Snapshot.LastEventID
+		replay = saved.Events[saved.Snapshot.LastEventID:]
+	} else if len(replay) == 0 || replay[0].Type != "ROOM_CREATED" {
+		return errNoHistory
+	}
+
+	for _, evt := range replay {
+		room.apply(evt.Payload)
+	}
+	room.Events = saved.Events
+	room.savedEvents = len(saved.Events)
+	room.syncTurnTimer()
+	return nil
+}
+
+// LoadRooms restores the unfinished rooms saved in the store, so players
+// can rejoin them after a restart. Finished games are archived, in case the
+// server stopped before it could, and dropped along with logs that can't
+// be replayed.
+func (gm *GameManager) LoadRooms() error {
+	saved, err := gm.store.LoadRooms()
+	if err != nil {
+		return err
+	}
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	restored := 0
+	for _, s := range saved {
+		room := newRoom(s.Code, gm.dice, gm.clock, gm.store)
+		err := room.rebuild(s)
+		if err != nil || room.Phase == PhaseGameOver {
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("room_code", s.Code).
+					Msg("Dropping saved room")
+			} else {
+				room.archiveGame()
+			}
+			room.close()
+			if err := gm.store.DeleteRoom(s.Code); err != nil {
+				return err
+			}
+			continue
+		}
+
+		gm.rooms[room.Code] = room
+		restored++
+	}
+
+	log.Info().
+		Int("rooms", restored).
+		Msg("Restored saved rooms")
+	return nil
+}
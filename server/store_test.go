@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRestoredRoomRejoinsSeatedPlayers(t *testing.T) {
+	store := newMemoryStore()
+	before := newTestServer(t, NewGameManager(Config{TokenKey: []byte("restart test key")}, store))
+	host := before.post("/rooms", map[string]interface{}{"player_name": "Alice"})
+	code, _ := host["room_code"].(string)
+	guest := before.post("/rooms/join", map[string]interface{}{"room_code": code, "player_name": "Bob"})
+	if errCode := before.command(code, host, map[string]interface{}{"type": "GAME_START"}); errCode != "" {
+		t.Fatalf("GAME_START: %s", errCode)
+	}
+	before.room(code).close()
+
+	// A new server sharing the signing key restores the room from the store
+	after := newTestServer(t, NewGameManager(Config{TokenKey: []byte("restart test key")}, store))
+	if err := after.gm.LoadRooms(); err != nil {
+		t.Fatal(err)
+	}
+	room := after.room(code)
+	if room == nil {
+		t.Fatal("room wasn't restored")
+	}
+
+	players := map[string]map[string]interface{}{}
+	for _, p := range []map[string]interface{}{host, guest} {
+		rejoined := after.post("/rooms/join", map[string]interface{}{
+			"room_code": code,
+			"player_id": p["player_id"],
+			"token":     p["token"],
+		})
+		if rejoined["is_viewer"] != false || rejoined["player_id"] != p["player_id"] {
+			t.Fatalf("rejoin = %v, want %v back in their seat", rejoined, p["player_id"])
+		}
+		players[rejoined["player_id"].(string)] = rejoined
+	}
+
+	var current string
+	room.do(func() { current = room.PlayerOrder[room.CurrentPlayerIdx] })
+	if errCode := after.command(code, players[current], map[string]interface{}{"type": "REQUEST_ROLL"}); errCode != "" {
+		t.Fatalf("REQUEST_ROLL after rejoining: %s", errCode)
+	}
+	room.do(func() {
+		if room.Phase != PhaseRolling || room.RollNumber != 1 {
+			t.Errorf("phase %s, roll %d after rolling, want rolling, roll 1", room.Phase, room.RollNumber)
+		}
+	})
+}
+
+// playSavedTurns plays whole turns in a room, saving after each command the
+// way its event loop would, until it has at least minEvents or the game ends
+func playSavedTurns(t *testing.T, room *Room, minEvents int) {
+	t.Helper()
+	for turn := 0; len(room.Events) < minEvents && room.Phase != PhaseGameOver; turn++ {
+		for _, cmd := range []Command{&RequestRollCommand{}, &CategoryChosenCommand{Category: classicCategories[turn/2]}} {
+			if err := room.processCommand(room.PlayerOrder[room.CurrentPlayerIdx], cmd); err != nil {
+				t.Fatalf("turn %d: %s: %v", turn+1, cmd.commandType(), err)
+			}
+			room.persist()
+		}
+	}
+}
+
+// rebuiltState rebuilds a room from saved and returns its state as JSON
+func rebuiltState(t *testing.T, saved SavedRoom) string {
+	t.Helper()
+	room := newRoom(saved.Code, NewSeededDice(1), realClock{}, nil)
+	defer room.close()
+	if err := room.rebuild(saved); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	return stateJSON(t, room)
+}
+
+func stateJSON(t *testing.T, room *Room) string {
+	t.Helper()
+	data, err := json.Marshal(room.snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRebuildMatchesPlayedRoom(t *testing.T) {
+	store := newMemoryStore()
+	room := startTestGame(t, RoomSettings{}, NewScriptedDice([]int{2, 3, 4, 5, 6}), realClock{})
+	room.store = store
+	room.persist()
+
+	playSavedTurns(t, room, snapshotInterval+10)
+	if err := room.processCommand(room.PlayerOrder[room.CurrentPlayerIdx], &RequestRollCommand{}); err != nil {
+		t.Fatalf("roll: %v", err)
+	}
+	room.persist()
+	want := stateJSON(t, room)
+
+	rooms, err := store.LoadRooms()
+	if err != nil || len(rooms) != 1 {
+		t.Fatalf("LoadRooms = %d rooms, %v; want 1", len(rooms), err)
+	}
+	saved := rooms[0]
+	if saved.Snapshot == nil || saved.Snapshot.LastEventID < snapshotInterval || saved.Snapshot.LastEventID >= len(saved.Events) {
+		t.Fatalf("want a snapshot with events after it; snapshot %+v, %d events", saved.Snapshot, len(saved.Events))
+	}
+
+	ahead := *saved.Snapshot
+	ahead.LastEventID = len(saved.Events) + 1
+	tests := []struct {
+		name  string
+		saved SavedRoom
+	}{
+		{"from the snapshot", saved},
+		{"from the full log", SavedRoom{Code: saved.Code, Events: saved.Events}},
+		{"snapshot ahead of the log", SavedRoom{Code: saved.Code, Snapshot: &ahead, Events: saved.Events}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rebuiltState(t, tt.saved); got != want {
+				t.Errorf("rebuilt state differs from the played room\ngot:  %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadRoomsArchivesFinishedGames(t *testing.T) {
+	room := startTestGame(t, RoomSettings{}, NewScriptedDice([]int{2, 3, 4, 5, 6}), realClock{})
+	playSavedTurns(t, room, math.MaxInt)
+	if room.Phase != PhaseGameOver {
+		t.Fatalf("phase %s after every turn, want game over", room.Phase)
+	}
+	end, _ := lastEvent(room, "GAME_END").(*GameEndEvent)
+
+	// The server stopped after saving the game's end but before archiving it
+	store := newMemoryStore()
+	if err := store.SaveRoom(room.Code, room.Events, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.LoadGame(end.GameID); !errors.Is(err, errGameNotFound) {
+		t.Fatalf("LoadGame before restoring: err = %v, want %v", err, errGameNotFound)
+	}
+
+	gm := NewGameManager(Config{}, store)
+	if err := gm.LoadRooms(); err != nil {
+		t.Fatal(err)
+	}
+	if _, restored := gm.rooms[room.Code]; restored {
+		t.Error("finished room was restored")
+	}
+	if rooms, _ := store.LoadRooms(); len(rooms) != 0 {
+		t.Errorf("store still has %d rooms", len(rooms))
+	}
+	game, err := store.LoadGame(end.GameID)
+	if err != nil {
+		t.Fatalf("LoadGame after restoring: %v", err)
+	}
+	if game.WinnerID != end.WinnerID || game.FinishedAt != time.UnixMilli(end.FinishedAt).UTC() {
+		t.Errorf("archived game = %+v, want the game that ended", game)
+	}
+}
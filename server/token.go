@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// defaultTokenTTL is how long session tokens last unless configured
+const defaultTokenTTL = 24 * time.Hour
+
+// defaultGuestTTL is how long guest credentials last unless configured.
+// They stand in for an account on one device, so they're long-lived.
+const defaultGuestTTL = 365 * 24 * time.Hour
+
+// Roles a session token can grant
+const (
+	RolePlayer  = "player"
+	RoleViewer  = "viewer"
+	RoleAccount = "account" // Signed in to an account rather than seated in a room
+	RoleGuest   = "guest"   // A device's guest identity, also not tied to a room
+)
+
+var (
+	errTokenMalformed = errors.New("malformed token")
+	errTokenSignature = errors.New("token signature mismatch")
+	errTokenExpired   = errors.New("token expired")
+)
+
+// sessionClaims is what a session token vouches for. Tokens carry
+// everything needed to check them, so they stay valid across restarts and
+// instances that share the signing key.
+type sessionClaims struct {
+	RoomCode string `json:"room"`
+	PlayerID string `json:"sub"`
+	Role     string `json:"role"`
+	Name     string `json:"name,omitempty"` // Guest credentials carry the guest's display name
+	Expires  int64  `json:"exp"`            // Unix seconds
+}
+
+// tokenSigner issues and checks HMAC-SHA256 signed session tokens of the
+// form base64url(claims) "." base64url(signature)
+type tokenSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+// newTokenSigner returns a signer for key. Without a key it makes up a
+// random one, so tokens only last as long as the process.
+func newTokenSigner(key []byte, ttl time.Duration) *tokenSigner {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	return &tokenSigner{key: key, ttl: ttl}
+}
+
+// issue signs a token granting role that expires ttl after now
+func (s *tokenSigner) issue(roomCode, playerID, role string, now time.Time) string {
+	return s.encode(sessionClaims{
+		RoomCode: roomCode,
+		PlayerID: playerID,
+		Role:     role,
+		Expires:  now.Add(s.ttl).Unix(),
+	})
+}
+
+// encode signs claims as they are, for tokens that don't last the usual ttl
+func (s *tokenSigner) encode(claims sessionClaims) string {
+	payload, _ := json.Marshal(claims)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded))
+}
+
+// verify checks a token's signature and expiry and returns its claims
+func (s *tokenSigner) verify(token string, now time.Time) (sessionClaims, error) {
+	var claims sessionClaims
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, errTokenMalformed
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return claims, errTokenMalformed
+	}
+	if !hmac.Equal(mac, s.sign(encoded)) {
+		return claims, errTokenSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return claims, errTokenMalformed
+	}
+	if now.Unix() >= claims.Expires {
+		return claims, errTokenExpired
+	}
+	return claims, nil
+}
+
+// sign computes the HMAC of a token's encoded claims
+func (s *tokenSigner) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenSigner(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	signer := newTokenSigner([]byte("signing key"), time.Hour)
+	token := signer.issue("ROOM01", "player-1", RolePlayer, now)
+	encoded, signature, _ := strings.Cut(token, ".")
+
+	// Claims re-encoded with a different player, keeping the signature
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"room":"ROOM01","sub":"player-2","role":"player","exp":1700003600}`)) + "." + signature
+
+	tests := []struct {
+		name   string
+		signer *tokenSigner
+		token  string
+		at     time.Time
+		err    error
+	}{
+		{"valid", signer, token, now, nil},
+		{"valid until it expires", signer, token, now.Add(time.Hour - time.Second), nil},
+		{"expired", signer, token, now.Add(time.Hour), errTokenExpired},
+		{"wrong key", newTokenSigner([]byte("another key"), time.Hour), token, now, errTokenSignature},
+		{"random key", newTokenSigner(nil, time.Hour), token, now, errTokenSignature},
+		{"forged claims", signer, forged, now, errTokenSignature},
+		{"changed signature", signer, encoded + "." + base64.RawURLEncoding.EncodeToString([]byte("not the signature")), now, errTokenSignature},
+		{"signature not base64", signer, encoded + ".!!", now, errTokenMalformed},
+		{"no signature", signer, encoded, now, errTokenMalformed},
+		{"empty", signer, "", now, errTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.signer.verify(tt.token, tt.at)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err == nil && (claims.RoomCode != "ROOM01" || claims.PlayerID != "player-1" || claims.Role != RolePlayer) {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestGuestCredentialClaims(t *testing.T) {
+	gm := NewGameManager(Config{GuestTTL: 48 * time.Hour}, newMemoryStore())
+	credential, expires := gm.issueGuestCredential("guest-1", "Robin")
+
+	claims, err := gm.tokens.verify(credential, gm.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.PlayerID != "guest-1" || claims.Name != "Robin" || claims.Role != RoleGuest || claims.RoomCode != "" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, err := gm.tokens.verify(credential, expires); !errors.Is(err, errTokenExpired) {
+		t.Errorf("credential at its expiry: err = %v, want %v", err, errTokenExpired)
+	}
+
+	// A guest credential never passes as a seat in a room
+	if _, err := gm.verifyToken("ROOM01", "guest-1", credential); !errors.Is(err, errTokenMismatch) {
+		t.Errorf("guest credential for a room: err = %v, want %v", err, errTokenMismatch)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TurnPhase tracks where the current player is within their turn
+type TurnPhase int
+
+const (
+	PhaseAwaitingRoll TurnPhase = iota // Turn started, dice not rolled yet
+	PhaseRolling                       // Rolled at least once, rolls remain
+	PhaseMustScore                     // No rolls left, a category must be filled
+	PhaseGameOver                      // Every scorecard is full
+)
+
+// Turn actions a player can take
+const (
+	ActionRoll    = "roll"
+	ActionScore   = "score"
+	ActionScratch = "scratch"
+)
+
+// phaseActions lists the actions each phase accepts
+var phaseActions = map[TurnPhase][]string{
+	PhaseAwaitingRoll: {ActionRoll, ActionScratch},
+	PhaseRolling:      {ActionRoll, ActionScore, ActionScratch},
+	PhaseMustScore:    {ActionScore, ActionScratch},
+	PhaseGameOver:     {},
+}
+
+var (
+	errGameNotStarted    = errors.New("game has not started")
+	errGameOver          = errors.New("game is over")
+	errNotYourTurn       = errors.New("not your turn")
+	errMustRollFirst     = errors.New("dice must be rolled before scoring")
+	errNoRollsLeft       = errors.New("no rolls left, a category must be chosen")
+	errCategoryRequired  = errors.New("a category is required")
+	errInvalidTransition = errors.New("action not allowed in this turn phase")
+)
+
+// String returns the phase name sent to clients
+func (p TurnPhase) String() string {
+	switch p {
+	case PhaseAwaitingRoll:
+		return "awaiting_roll"
+	case PhaseRolling:
+		return "rolling"
+	case PhaseMustScore:
+		return "must_score"
+	case PhaseGameOver:
+		return "game_over"
+	}
+	return "unknown"
+}
+
+// Allows reports whether the phase accepts an action
+func (p TurnPhase) Allows(action string) bool {
+	for _, a := range phaseActions[p] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// phaseError explains why a phase rejects an action
+func (p TurnPhase) phaseError(action string) error {
+	switch {
+	case p == PhaseGameOver:
+		return errGameOver
+	case p == PhaseAwaitingRoll && action == ActionScore:
+		return errMustRollFirst
+	case p == PhaseMustScore && action == ActionRoll:
+		return errNoRollsLeft
+	}
+	return errInvalidTransition
+}
+
+// afterRoll returns the phase reached once a roll leaves rollsLeft rolls
+func afterRoll(rollsLeft int) TurnPhase {
+	if rollsLeft > 0 {
+		return PhaseRolling
+	}
+	return PhaseMustScore
+}
+
+// checkTurnAction validates that a player may take an action right now
+func (room *Room) checkTurnAction(playerID, action string) error {
+	if !room.GameStarted {
+		return errGameNotStarted
+	}
+	if room.Phase == PhaseGameOver {
+		return errGameOver
+	}
+	if len(room.PlayerOrder) == 0 || room.PlayerOrder[room.CurrentPlayerIdx] != playerID {
+		return errNotYourTurn
+	}
+	if !room.Phase.Allows(action) {
+		return room.Phase.phaseError(action)
+	}
+	return nil
+}
+
+// syncTurnTimer arms the timer for the current turn's deadline, replacing
+// any earlier one. A deadline that passed while the server was down fires
+// at once.
+func (room *Room) syncTurnTimer() {
+	room.stopTurnTimer()
+	if !room.GameStarted || room.Phase == PhaseGameOver || room.TurnDeadline.IsZero() {
+		return
+	}
+	room.armTurnTimer(room.TurnDeadline.Sub(room.clock.Now()))
+}
+
+// armTurnTimer plays out the current turn once d has elapsed
+func (room *Room) armTurnTimer(d time.Duration) {
+	turn := room.TurnNumber
+	room.turnTimer = room.clock.AfterFunc(d, func() {
+		room.do(func() { room.handleTurnTimeout(turn) })
+	})
+}
+
+// stopTurnTimer cancels any pending turn deadline
+func (room *Room) stopTurnTimer() {
+	if room.turnTimer != nil {
+		room.turnTimer.Stop()
+		room.turnTimer = nil
+	}
+}
+
+// nextTurnTiming reports the deadline of a turn starting now, with the
+// server clock so clients can show a countdown that doesn't depend on
+// their own clock
+func (room *Room) nextTurnTiming() TurnTiming {
+	now := room.clock.Now()
+	timing := TurnTiming{
+		ServerTime:         now.UnixMilli(),
+		TurnTimeoutSeconds: int(room.TurnTimeout / time.Second),
+	}
+	if room.TurnTimeout > 0 {
+		deadline := now.Add(room.TurnTimeout).UnixMilli()
+		timing.TurnDeadline = &deadline
+	}
+	return timing
+}
+
+// handleTurnTimeout plays out a turn whose deadline passed: it rolls if the
+// player never did, then fills the open category worth the fewest points
+func (room *Room) handleTurnTimeout(turn int) {
+	if turn != room.TurnNumber || !room.GameStarted || room.Phase == PhaseGameOver || len(room.PlayerOrder) == 0 {
+		return
+	}
+	playerID := room.PlayerOrder[room.CurrentPlayerIdx]
+
+	log.Info().
+		Str("room_code", room.Code).
+		Str("player_id", playerID).
+		Int("turn", turn).
+		Str("phase", room.Phase.String()).
+		Msg("Turn timed out")
+
+	room.addEvent("TURN_TIMEOUT", &TurnTimeoutEvent{
+		PlayerID: playerID,
+	})
+
+	if room.Phase == PhaseAwaitingRoll {
+		if err := room.handleRequestRoll(playerID, &RequestRollCommand{}); err != nil {
+			log.Warn().
+				Err(err).
+				Str("room_code", room.Code).
+				Str("player_id", playerID).
+				Msg("Auto-roll on turn timeout failed")
+			return
+		}
+	}
+
+	category, ok := "", false
+	if player, exists := room.Players[playerID]; exists {
+		category, ok = lowestOpenCategory(room.Rules, player.Scores, room.CurrentDice)
+	}
+	if !ok {
+		return
+	}
+
+	if err := room.fillCategory(playerID, category, false); err != nil {
+		log.Warn().
+			Err(err).
+			Str("room_code", room.Code).
+			Str("player_id", playerID).
+			Str("category", category).
+			Msg("Auto-score on turn timeout failed")
+	}
+}
+
+// lowestOpenCategory returns the open category the dice score fewest
+// points in, preferring earlier categories on ties
+func lowestOpenCategory(rules Ruleset, scores map[string]int, dice []int) (string, bool) {
+	best, bestScore := "", 0
+	for _, category := range rules.Categories() {
+		score, bonus, err := rules.ScoreTurn(scores, category, dice)
+		if err != nil {
+			continue
+		}
+		if best == "" || score+bonus < bestScore {
+			best, bestScore = category, score+bonus
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// waitForRoom polls the room on its loop until cond holds. Timers fire in
+// their own goroutine, so their work reaches the room a little after the
+// clock moves.
+func waitForRoom(t *testing.T, room *Room, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var done bool
+		room.do(func() { done = cond() })
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the room")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// countEvents returns how many of the room's events have a type
+func countEvents(room *Room, eventType string) int {
+	count := 0
+	for _, e := range room.Events {
+		if e.Type == eventType {
+			count++
+		}
+	}
+	return count
+}
+
+func TestTurnTimeoutPlaysTheTurn(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	room := startTestGame(t, RoomSettings{TurnTimerSeconds: 30}, NewScriptedDice([]int{1, 1, 2, 3, 6}), clock)
+
+	clock.Advance(29 * time.Second)
+	room.do(func() {
+		if n := countEvents(room, "TURN_TIMEOUT"); n != 0 {
+			t.Errorf("%d turns timed out before the deadline", n)
+		}
+	})
+
+	clock.Advance(time.Second)
+	waitForRoom(t, room, func() bool { return countEvents(room, "TURN_CHANGED") == 1 })
+	room.do(func() {
+		timeout, _ := lastEvent(room, "TURN_TIMEOUT").(*TurnTimeoutEvent)
+		if timeout == nil || timeout.PlayerID != "P1" {
+			t.Errorf("TURN_TIMEOUT = %+v, want one for P1", timeout)
+		}
+		if n := countEvents(room, "ROLL_RESULT"); n != 1 {
+			t.Errorf("%d rolls, want P1's turn auto-rolled once", n)
+		}
+		// 1 1 2 3 6 scores nothing first in fours
+		update, _ := lastEvent(room, "SCORE_UPDATE").(*ScoreUpdateEvent)
+		if update == nil || update.PlayerID != "P1" || update.Category != "fours" || update.Score != 0 || update.Scratched {
+			t.Errorf("SCORE_UPDATE = %+v, want P1 scoring 0 in fours", update)
+		}
+		if current := room.PlayerOrder[room.CurrentPlayerIdx]; current != "P2" {
+			t.Errorf("current player = %q, want P2", current)
+		}
+	})
+
+	// P2 plays in time, so only P1's next turn times out
+	var err error
+	room.do(func() {
+		err = room.processCommand("P2", &RequestRollCommand{})
+		if err == nil {
+			err = room.processCommand("P2", &CategoryChosenCommand{Category: "ones"})
+		}
+	})
+	if err != nil {
+		t.Fatalf("P2's turn: %v", err)
+	}
+	clock.Advance(30 * time.Second)
+	waitForRoom(t, room, func() bool { return countEvents(room, "TURN_CHANGED") == 3 })
+	room.do(func() {
+		timeout, _ := lastEvent(room, "TURN_TIMEOUT").(*TurnTimeoutEvent)
+		if n := countEvents(room, "TURN_TIMEOUT"); n != 2 || timeout == nil || timeout.PlayerID != "P1" {
+			t.Errorf("%d timeouts, last for %+v; want a second one for P1", n, timeout)
+		}
+	})
+}
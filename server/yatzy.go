@@ -0,0 +1,164 @@
+package main
+
+// YatzyRuleset implements the Scandinavian Yatzy family. Combinations score
+// the dice that form them, straights have fixed values and there are no
+// Joker rules. Maxi Yatzy adds a sixth die, extra categories and lets
+// players save unused rolls for later turns.
+type YatzyRuleset struct {
+	name           string
+	diceCount      int
+	categories     []string
+	bonusThreshold int
+	bonus          int
+	saveRolls      bool
+}
+
+// YatzyRules is Scandinavian Yatzy played with five dice
+var YatzyRules = YatzyRuleset{
+	name:      "yatzy",
+	diceCount: 5,
+	categories: []string{
+		"ones", "twos", "threes", "fours", "fives", "sixes",
+		"one_pair", "two_pairs", "three_of_a_kind", "four_of_a_kind",
+		"small_straight", "large_straight", "full_house", "chance", "yatzy",
+	},
+	bonusThreshold: 63,
+	bonus:          50,
+}
+
+// MaxiYatzyRules is Maxi Yatzy played with six dice and saved rolls
+var MaxiYatzyRules = YatzyRuleset{
+	name:      "maxi_yatzy",
+	diceCount: 6,
+	categories: []string{
+		"ones", "twos", "threes", "fours", "fives", "sixes",
+		"one_pair", "two_pairs", "three_pairs",
+		"three_of_a_kind", "four_of_a_kind", "five_of_a_kind",
+		"small_straight", "large_straight", "full_straight",
+		"full_house", "castle", "tower", "chance", "maxi_yatzy",
+	},
+	bonusThreshold: 84,
+	bonus:          50,
+	saveRolls:      true,
+}
+
+func init() {
+	rulesets[YatzyRules.name] = YatzyRules
+	rulesets[MaxiYatzyRules.name] = MaxiYatzyRules
+}
+
+// Name implements Ruleset
+func (r YatzyRuleset) Name() string { return r.name }
+
+// Categories implements Ruleset
+func (r YatzyRuleset) Categories() []string { return r.categories }
+
+// DiceCount implements Ruleset
+func (r YatzyRuleset) DiceCount() int { return r.diceCount }
+
+// RollsPerTurn implements Ruleset
+func (r YatzyRuleset) RollsPerTurn() int { return 3 }
+
+// SavesUnusedRolls implements Ruleset
+func (r YatzyRuleset) SavesUnusedRolls() bool { return r.saveRolls }
+
+// UpperBonus implements Ruleset
+func (r YatzyRuleset) UpperBonus(scores map[string]int) int {
+	if upperTotal(scores) >= r.bonusThreshold {
+		return r.bonus
+	}
+	return 0
+}
+
+// ScoreTurn implements Ruleset. Yatzy has no bonus for repeated Yatzys.
+func (r YatzyRuleset) ScoreTurn(scores map[string]int, category string, dice []int) (score int, bonus int, err error) {
+	if !hasCategory(r, category) {
+		return 0, 0, errUnknownCategory
+	}
+	if _, taken := scores[category]; taken {
+		return 0, 0, errCategoryTaken
+	}
+	if !allRolled(dice) {
+		return 0, 0, nil
+	}
+	return r.scoreCategory(category, dice), 0, nil
+}
+
+// scoreCategory computes the score the dice earn in a Yatzy category
+func (r YatzyRuleset) scoreCategory(category string, dice []int) int {
+	switch category {
+	case "one_pair":
+		return groupScore(dice, 2)
+	case "two_pairs":
+		return groupScore(dice, 2, 2)
+	case "three_pairs":
+		return groupScore(dice, 2, 2, 2)
+	case "three_of_a_kind":
+		return groupScore(dice, 3)
+	case "four_of_a_kind":
+		return groupScore(dice, 4)
+	case "five_of_a_kind":
+		return groupScore(dice, 5)
+	case "full_house":
+		return groupScore(dice, 3, 2)
+	case "castle":
+		return groupScore(dice, 3, 3)
+	case "tower":
+		return groupScore(dice, 4, 2)
+	case "small_straight":
+		if hasRun(dice, 1, 2, 3, 4, 5) {
+			return 15
+		}
+	case "large_straight":
+		if hasRun(dice, 2, 3, 4, 5, 6) {
+			return 20
+		}
+	case "full_straight":
+		if hasRun(dice, 1, 2, 3, 4, 5, 6) {
+			return 21
+		}
+	case "chance":
+		return sumDice(dice)
+	case "yatzy":
+		if hasNOfAKind(dice, 5) {
+			return 50
+		}
+	case "maxi_yatzy":
+		if hasNOfAKind(dice, 6) {
+			return 100
+		}
+	default:
+		return scoreUpper(category, dice)
+	}
+	return 0
+}
+
+// groupScore finds distinct faces forming groups of the given sizes and
+// returns the highest possible total of the grouped dice, or 0 if the
+// dice cannot form every group
+func groupScore(dice []int, sizes ...int) int {
+	counts := countFaces(dice)
+	used := make(map[int]bool)
+	best := 0
+
+	var pick func(i, total int)
+	pick = func(i, total int) {
+		if i == len(sizes) {
+			if total > best {
+				best = total
+			}
+			return
+		}
+		for face := 6; face >= 1; face-- {
+			if used[face] || counts[face] < sizes[i] {
+				continue
+			}
+			used[face] = true
+			pick(i+1, total+face*sizes[i])
+			used[face] = false
+		}
+	}
+	pick(0, 0)
+
+	return best
+}
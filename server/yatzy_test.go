@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestYatzyScoreTurn(t *testing.T) {
+	tests := []struct {
+		category string
+		dice     []int
+		want     int
+	}{
+		{"fives", []int{5, 5, 1, 2, 5}, 15},
+		{"one_pair", []int{3, 3, 5, 5, 1}, 10},
+		{"one_pair", []int{1, 2, 3, 4, 6}, 0},
+		{"two_pairs", []int{3, 3, 5, 5, 1}, 16},
+		{"two_pairs", []int{3, 3, 3, 3, 1}, 0},
+		{"three_of_a_kind", []int{2, 2, 2, 6, 6}, 6},
+		{"four_of_a_kind", []int{2, 2, 2, 2, 6}, 8},
+		{"four_of_a_kind", []int{6, 6, 6, 6, 6}, 24},
+		{"full_house", []int{2, 2, 2, 6, 6}, 18},
+		{"full_house", []int{6, 6, 6, 6, 6}, 0},
+		{"small_straight", []int{5, 4, 3, 2, 1}, 15},
+		{"small_straight", []int{2, 3, 4, 5, 6}, 0},
+		{"large_straight", []int{2, 3, 4, 5, 6}, 20},
+		{"large_straight", []int{1, 2, 3, 4, 5}, 0},
+		{"chance", []int{1, 2, 3, 4, 6}, 16},
+		{"yatzy", []int{3, 3, 3, 3, 3}, 50},
+		{"yatzy", []int{3, 3, 3, 3, 2}, 0},
+		{"chance", []int{0, 0, 0, 0, 0}, 0},
+	}
+
+	for _, tt := range tests {
+		got, bonus, err := YatzyRules.ScoreTurn(map[string]int{}, tt.category, tt.dice)
+		if err != nil {
+			t.Fatalf("ScoreTurn(%q, %v): %v", tt.category, tt.dice, err)
+		}
+		if got != tt.want || bonus != 0 {
+			t.Errorf("ScoreTurn(%q, %v) = %d bonus %d, want %d bonus 0", tt.category, tt.dice, got, bonus, tt.want)
+		}
+	}
+}
+
+func TestMaxiYatzyScoreTurn(t *testing.T) {
+	tests := []struct {
+		category string
+		dice     []int
+		want     int
+	}{
+		{"three_pairs", []int{1, 1, 4, 4, 6, 6}, 22},
+		{"three_pairs", []int{4, 4, 4, 4, 6, 6}, 0},
+		{"two_pairs", []int{5, 5, 5, 5, 6, 6}, 22},
+		{"five_of_a_kind", []int{2, 2, 2, 2, 2, 6}, 10},
+		{"castle", []int{3, 3, 3, 5, 5, 5}, 24},
+		{"tower", []int{3, 3, 3, 3, 5, 5}, 22},
+		{"tower", []int{3, 3, 3, 5, 5, 5}, 0},
+		{"full_house", []int{3, 3, 3, 5, 5, 5}, 21},
+		{"small_straight", []int{1, 2, 3, 4, 5, 5}, 15},
+		{"large_straight", []int{6, 2, 3, 4, 5, 5}, 20},
+		{"full_straight", []int{6, 5, 4, 3, 2, 1}, 21},
+		{"full_straight", []int{6, 5, 4, 3, 2, 2}, 0},
+		{"maxi_yatzy", []int{6, 6, 6, 6, 6, 6}, 100},
+		{"maxi_yatzy", []int{6, 6, 6, 6, 6, 1}, 0},
+	}
+
+	for _, tt := range tests {
+		got, _, err := MaxiYatzyRules.ScoreTurn(map[string]int{}, tt.category, tt.dice)
+		if err != nil {
+			t.Fatalf("ScoreTurn(%q, %v): %v", tt.category, tt.dice, err)
+		}
+		if got != tt.want {
+			t.Errorf("ScoreTurn(%q, %v) = %d, want %d", tt.category, tt.dice, got, tt.want)
+		}
+	}
+}
+
+func TestYatzyRulesetErrors(t *testing.T) {
+	if _, _, err := YatzyRules.ScoreTurn(map[string]int{}, "yahtzee", []int{1, 1, 1, 1, 1}); !errors.Is(err, errUnknownCategory) {
+		t.Errorf("yahtzee in yatzy: err = %v, want %v", err, errUnknownCategory)
+	}
+	if _, _, err := YatzyRules.ScoreTurn(map[string]int{}, "castle", []int{1, 1, 1, 2, 2}); !errors.Is(err, errUnknownCategory) {
+		t.Errorf("castle in yatzy: err = %v, want %v", err, errUnknownCategory)
+	}
+	if _, _, err := YatzyRules.ScoreTurn(map[string]int{"chance": 0}, "chance", []int{1, 1, 1, 2, 2}); !errors.Is(err, errCategoryTaken) {
+		t.Errorf("taken chance: err = %v, want %v", err, errCategoryTaken)
+	}
+
+	// A second Yatzy earns nothing extra
+	_, bonus, err := YatzyRules.ScoreTurn(map[string]int{"yatzy": 50}, "fives", []int{5, 5, 5, 5, 5})
+	if err != nil || bonus != 0 {
+		t.Errorf("second yatzy: bonus %d err %v, want bonus 0", bonus, err)
+	}
+}
+
+func TestYatzyUpperBonus(t *testing.T) {
+	tests := []struct {
+		rules  YatzyRuleset
+		scores map[string]int
+		want   int
+	}{
+		{YatzyRules, map[string]int{"ones": 3, "twos": 6, "threes": 9, "fours": 12, "fives": 15, "sixes": 18}, 50},
+		{YatzyRules, map[string]int{"ones": 2, "twos": 6, "threes": 9, "fours": 12, "fives": 15, "sixes": 18}, 0},
+		{MaxiYatzyRules, map[string]int{"ones": 4, "twos": 8, "threes": 12, "fours": 16, "fives": 20, "sixes": 24}, 50},
+		{MaxiYatzyRules, map[string]int{"ones": 3, "twos": 6, "threes": 9, "fours": 12, "fives": 15, "sixes": 30}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rules.UpperBonus(tt.scores); got != tt.want {
+			t.Errorf("%s UpperBonus(%v) = %d, want %d", tt.rules.Name(), tt.scores, got, tt.want)
+		}
+	}
+}
+
+func TestLookupRuleset(t *testing.T) {
+	tests := []struct {
+		variant   string
+		want      string
+		diceCount int
+		saves     bool
+	}{
+		{"", "yahtzee", 5, false},
+		{"Yatzy", "yatzy", 5, false},
+		{"maxi_yatzy", "maxi_yatzy", 6, true},
+	}
+
+	for _, tt := range tests {
+		rules, ok := LookupRuleset(tt.variant)
+		if !ok {
+			t.Fatalf("LookupRuleset(%q) found nothing", tt.variant)
+		}
+		if rules.Name() != tt.want || rules.DiceCount() != tt.diceCount || rules.SavesUnusedRolls() != tt.saves {
+			t.Errorf("LookupRuleset(%q) = %s with %d dice, saves rolls %v", tt.variant, rules.Name(), rules.DiceCount(), rules.SavesUnusedRolls())
+		}
+	}
+
+	if _, ok := LookupRuleset("farkle"); ok {
+		t.Error("LookupRuleset found an unknown variant")
+	}
+}